@@ -0,0 +1,148 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// ClusterGithubActionRunnerClassSpec is a runner pool definition shared by GithubActionRunners in any namespace
+type ClusterGithubActionRunnerClassSpec struct {
+	// Pod template the podTemplateSpec of GithubActionRunners is merged into, like a strategic merge patch.
+	// Containers, volumes and environment variables are merged by name.
+	// +kubebuilder:validation:Required
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Pod Template"
+	PodTemplateSpec v1.PodTemplateSpec `json:"podTemplateSpec"`
+
+	// Container runtime sidecar, used unless the GithubActionRunner selects a runtime other than None
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Container Runtime",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:select:None","urn:alm:descriptor:com.tectonic.ui:select:Dind","urn:alm:descriptor:com.tectonic.ui:select:RootlessDind","urn:alm:descriptor:com.tectonic.ui:select:BuildKit"}
+	ContainerRuntime ContainerRuntime `json:"containerRuntime,omitempty"`
+
+	// PAT to un/register runners, read from the namespace of the GithubActionRunner. Cannot be combined with appSecretRef.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Token Reference"
+	TokenRef *v1.SecretKeySelector `json:"tokenRef,omitempty"`
+
+	// GitHub App to un/register runners, read from the namespace of the GithubActionRunner. Cannot be combined with tokenRef.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="GitHub App Secret Reference"
+	AppSecretRef *GithubAppSecretRef `json:"appSecretRef,omitempty"`
+
+	// Where the secrets referenced by tokenRef and appSecretRef are read from
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="Secret"
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Credentials Source",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:select:Secret","urn:alm:descriptor:com.tectonic.ui:select:Vault","urn:alm:descriptor:com.tectonic.ui:select:File"}
+	CredentialsSource CredentialsSource `json:"credentialsSource,omitempty"`
+
+	// GitHub App installation to use, looked up by organization if not set
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="GitHub App Installation ID",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:number"}
+	InstallationID int64 `json:"installationId,omitempty"`
+}
+
+// ClusterGithubActionRunnerClass is the Schema for the clustergithubactionrunnerclasses API
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=clustergithubactionrunnerclasses,scope=Cluster,shortName=garc
+// +operator-sdk:csv:customresourcedefinitions:displayName="GitHub Actions Runner Class"
+type ClusterGithubActionRunnerClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterGithubActionRunnerClassSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterGithubActionRunnerClassList contains a list of ClusterGithubActionRunnerClass
+type ClusterGithubActionRunnerClassList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterGithubActionRunnerClass `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterGithubActionRunnerClass{}, &ClusterGithubActionRunnerClassList{})
+}
+
+// ApplyClass merges the class into the spec, the spec taking precedence.
+// The pod template of the spec is merged into the one of the class, credentials of the class are used if the spec
+// references none, and the container runtime of the class if the spec selects none.
+func (r *GithubActionRunnerSpec) ApplyClass(class *ClusterGithubActionRunnerClassSpec) error {
+	template, err := mergePodTemplate(class.PodTemplateSpec, r.PodTemplateSpec)
+	if err != nil {
+		return err
+	}
+	r.PodTemplateSpec = template
+
+	if r.TokenRef.Name == "" && r.AppSecretRef == nil {
+		if class.TokenRef != nil {
+			r.TokenRef = *class.TokenRef.DeepCopy()
+		}
+		r.AppSecretRef = class.AppSecretRef.DeepCopy()
+		r.CredentialsSource = class.CredentialsSource
+		if r.InstallationID == 0 {
+			r.InstallationID = class.InstallationID
+		}
+	}
+
+	if r.ContainerRuntime == "" || r.ContainerRuntime == NoContainerRuntime {
+		r.ContainerRuntime = class.ContainerRuntime
+	}
+
+	return nil
+}
+
+// mergePodTemplate applies the override to the base template as a strategic merge patch.
+// Fields the override leaves unset keep the value of the base template, instead of being cleared by the null
+// they are serialized to.
+func mergePodTemplate(base v1.PodTemplateSpec, override v1.PodTemplateSpec) (v1.PodTemplateSpec, error) {
+	baseJSON, err := json.Marshal(base)
+	if err != nil {
+		return v1.PodTemplateSpec{}, err
+	}
+	overrideJSON, err := json.Marshal(override)
+	if err != nil {
+		return v1.PodTemplateSpec{}, err
+	}
+
+	var patch map[string]interface{}
+	if err := json.Unmarshal(overrideJSON, &patch); err != nil {
+		return v1.PodTemplateSpec{}, err
+	}
+	patchJSON, err := json.Marshal(withoutNulls(patch))
+	if err != nil {
+		return v1.PodTemplateSpec{}, err
+	}
+
+	mergedJSON, err := strategicpatch.StrategicMergePatch(baseJSON, patchJSON, v1.PodTemplateSpec{})
+	if err != nil {
+		return v1.PodTemplateSpec{}, err
+	}
+	merged := v1.PodTemplateSpec{}
+	err = json.Unmarshal(mergedJSON, &merged)
+
+	return merged, err
+}
+
+func withoutNulls(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, element := range typed {
+			if element == nil {
+				delete(typed, key)
+				continue
+			}
+			typed[key] = withoutNulls(element)
+		}
+	case []interface{}:
+		for i, element := range typed {
+			typed[i] = withoutNulls(element)
+		}
+	}
+
+	return value
+}
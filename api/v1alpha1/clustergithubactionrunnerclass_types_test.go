@@ -0,0 +1,68 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func testClass() *ClusterGithubActionRunnerClassSpec {
+	return &ClusterGithubActionRunnerClassSpec{
+		PodTemplateSpec: v1.PodTemplateSpec{
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{
+					{Name: "runner", Image: "classImage", Env: []v1.EnvVar{{Name: "A", Value: "class"}, {Name: "B", Value: "class"}}},
+					{Name: "exporter", Image: "exporterImage"},
+				},
+				NodeSelector: map[string]string{"pool": "runners"},
+			},
+		},
+		ContainerRuntime: DindRuntime,
+		TokenRef: &v1.SecretKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{Name: "classSecret"},
+			Key:                  "GH_TOKEN",
+		},
+		CredentialsSource: VaultSource,
+	}
+}
+
+func TestApplyClass(t *testing.T) {
+	spec := GithubActionRunnerSpec{
+		Organization: "someOrg",
+		PodTemplateSpec: v1.PodTemplateSpec{
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{{Name: "runner", Env: []v1.EnvVar{{Name: "B", Value: "runner"}}}},
+			},
+		},
+		CredentialsSource: SecretSource,
+		ContainerRuntime:  NoContainerRuntime,
+	}
+
+	assert.NoError(t, spec.ApplyClass(testClass()))
+	containers := spec.PodTemplateSpec.Spec.Containers
+	assert.Len(t, containers, 2)
+	assert.Equal(t, "runner", containers[0].Name)
+	assert.Equal(t, "classImage", containers[0].Image, "unset fields are taken from the class")
+	assert.ElementsMatch(t, []v1.EnvVar{{Name: "A", Value: "class"}, {Name: "B", Value: "runner"}}, containers[0].Env)
+	assert.Equal(t, "exporterImage", containers[1].Image)
+	assert.Equal(t, map[string]string{"pool": "runners"}, spec.PodTemplateSpec.Spec.NodeSelector)
+	assert.Equal(t, DindRuntime, spec.ContainerRuntime)
+	assert.Equal(t, "classSecret", spec.TokenRef.Name)
+	assert.Equal(t, VaultSource, spec.CredentialsSource)
+}
+
+func TestApplyClassKeepsOwnSettings(t *testing.T) {
+	spec := GithubActionRunnerSpec{
+		AppSecretRef:      &GithubAppSecretRef{Name: "appSecret"},
+		CredentialsSource: SecretSource,
+		ContainerRuntime:  BuildKitRuntime,
+	}
+
+	assert.NoError(t, spec.ApplyClass(testClass()))
+	assert.Len(t, spec.PodTemplateSpec.Spec.Containers, 2)
+	assert.Empty(t, spec.TokenRef.Name)
+	assert.Equal(t, "appSecret", spec.AppSecretRef.Name)
+	assert.Equal(t, SecretSource, spec.CredentialsSource)
+	assert.Equal(t, BuildKitRuntime, spec.ContainerRuntime)
+}
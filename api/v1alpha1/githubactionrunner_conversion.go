@@ -0,0 +1,5 @@
+package v1alpha1
+
+// Hub marks v1alpha1 as the version all other versions of GithubActionRunner are converted through.
+// It is also the storage version and the version the controller works with.
+func (*GithubActionRunner) Hub() {}
@@ -0,0 +1,321 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// registration tokens issued by GitHub are valid for an hour
+const registrationTokenLifetime = time.Hour
+
+var organizationPattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]{0,37}[A-Za-z0-9])?$`)
+var repositoryPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,100}$`)
+
+// SetupWebhookWithManager registers the defaulting and validating webhooks of GithubActionRunner
+func (r *GithubActionRunner) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&githubActionRunnerDefaulter{}).
+		// read directly, the cache of the manager may be limited to the watched namespaces
+		WithValidator(&githubActionRunnerValidator{reader: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-garo-tietoevry-com-v1alpha1-githubactionrunner,mutating=true,failurePolicy=fail,sideEffects=None,groups=garo.tietoevry.com,resources=githubactionrunners,verbs=create;update,versions=v1alpha1,name=mgithubactionrunner.garo.tietoevry.com,admissionReviewVersions=v1
+
+// +kubebuilder:object:generate=false
+type githubActionRunnerDefaulter struct{}
+
+// Default fills in the defaults of the CRD schema, and picks the runner container of single container pod templates.
+// runnerContainer has no default in the CRD schema, which would be applied before this.
+func (d *githubActionRunnerDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	runner, ok := obj.(*GithubActionRunner)
+	if !ok {
+		return fmt.Errorf("expected a GithubActionRunner but got %T", obj)
+	}
+	spec := &runner.Spec
+
+	if spec.RunnerContainer == "" {
+		spec.RunnerContainer = "runner"
+		// the containers of a class template are unknown here
+		if containers := spec.PodTemplateSpec.Spec.Containers; len(containers) == 1 && spec.ClassName == "" {
+			spec.RunnerContainer = containers[0].Name
+		}
+	}
+	if spec.ReconciliationPeriod.Duration == 0 {
+		spec.ReconciliationPeriod = metav1.Duration{Duration: time.Minute}
+	}
+	if spec.RegistrationTokenRefreshMargin.Duration == 0 {
+		spec.RegistrationTokenRefreshMargin = metav1.Duration{Duration: 5 * time.Minute}
+	}
+	if spec.DeletionOrder == "" {
+		spec.DeletionOrder = LeastRecent
+	}
+	if spec.CredentialsSource == "" {
+		spec.CredentialsSource = SecretSource
+	}
+	if spec.PodNaming == "" {
+		spec.PodNaming = GeneratedPodNaming
+	}
+	if spec.ContainerRuntime == "" {
+		spec.ContainerRuntime = NoContainerRuntime
+	}
+	if spec.Registration.Mode == "" {
+		spec.Registration.Mode = EnvironmentRegistration
+	}
+	if spec.Registration.RunnerHome == "" {
+		spec.Registration.RunnerHome = "/home/runner"
+	}
+	if spec.SharedCache != nil && spec.SharedCache.RefreshInterval.Duration == 0 {
+		spec.SharedCache.RefreshInterval = metav1.Duration{Duration: 24 * time.Hour}
+	}
+
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-garo-tietoevry-com-v1alpha1-githubactionrunner,mutating=false,failurePolicy=fail,sideEffects=None,groups=garo.tietoevry.com,resources=githubactionrunners,verbs=create;update,versions=v1alpha1,name=vgithubactionrunner.garo.tietoevry.com,admissionReviewVersions=v1
+
+// +kubebuilder:object:generate=false
+type githubActionRunnerValidator struct {
+	reader client.Reader
+}
+
+// ValidateCreate rejects invalid specs, references to missing secrets and pools overlapping with existing pools
+func (v *githubActionRunnerValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	runner, ok := obj.(*GithubActionRunner)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubActionRunner but got %T", obj)
+	}
+
+	return nil, v.validate(ctx, runner, nil)
+}
+
+// ValidateUpdate validates like ValidateCreate when the spec changed, but only checks secrets if their reference changed
+func (v *githubActionRunnerValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	runner, ok := newObj.(*GithubActionRunner)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubActionRunner but got %T", newObj)
+	}
+	old, ok := oldObj.(*GithubActionRunner)
+	if !ok {
+		return nil, fmt.Errorf("expected a GithubActionRunner but got %T", oldObj)
+	}
+	// updates of the metadata only, like the controller adding or releasing its finalizer, are always accepted
+	if equality.Semantic.DeepEqual(old.Spec, runner.Spec) {
+		return nil, nil
+	}
+
+	return nil, v.validate(ctx, runner, old)
+}
+
+// ValidateDelete accepts all deletions
+func (v *githubActionRunnerValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *githubActionRunnerValidator) validate(ctx context.Context, runner *GithubActionRunner, old *GithubActionRunner) error {
+	specPath := field.NewPath("spec")
+	runner, classErrs, err := v.withClass(ctx, runner, specPath)
+	if err != nil {
+		return err
+	}
+	if len(classErrs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("GithubActionRunner").GroupKind(), runner.Name, classErrs)
+	}
+	if old != nil {
+		// compare like with like when checking if credentials changed
+		if old, _, err = v.withClass(ctx, old, specPath); err != nil {
+			return err
+		}
+	}
+	errs := runner.Spec.validate(specPath)
+
+	credentialErrs, err := v.validateCredentials(ctx, runner, old, specPath)
+	if err != nil {
+		return err
+	}
+	errs = append(errs, credentialErrs...)
+
+	poolErrs, err := v.validateUniquePool(ctx, runner, specPath)
+	if err != nil {
+		return err
+	}
+	errs = append(errs, poolErrs...)
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("GithubActionRunner").GroupKind(), runner.Name, errs)
+}
+
+// withClass returns a copy of the runner with the class it references applied, the spec of runners without a class
+// is returned as is
+func (v *githubActionRunnerValidator) withClass(ctx context.Context, runner *GithubActionRunner, specPath *field.Path) (*GithubActionRunner, field.ErrorList, error) {
+	if runner.Spec.ClassName == "" {
+		return runner, nil, nil
+	}
+
+	class := &ClusterGithubActionRunnerClass{}
+	if err := v.reader.Get(ctx, client.ObjectKey{Name: runner.Spec.ClassName}, class); err != nil {
+		if apierrors.IsNotFound(err) {
+			return runner, field.ErrorList{field.NotFound(specPath.Child("className"), runner.Spec.ClassName)}, nil
+		}
+		return nil, nil, err
+	}
+
+	merged := runner.DeepCopy()
+	if err := merged.Spec.ApplyClass(&class.Spec); err != nil {
+		return runner, field.ErrorList{field.Invalid(specPath.Child("podTemplateSpec"), runner.Spec.ClassName, err.Error())}, nil
+	}
+
+	return merged, nil, nil
+}
+
+// validate checks the spec on its own, in addition to IsValid which is also checked when reconciling
+func (r GithubActionRunnerSpec) validate(specPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if ok, err := r.IsValid(); !ok {
+		errs = append(errs, field.Forbidden(specPath, err.Error()))
+	}
+
+	if !organizationPattern.MatchString(r.Organization) {
+		errs = append(errs, field.Invalid(specPath.Child("organization"), r.Organization, "must be a GitHub user or organization name"))
+	}
+	if r.Repository != "" && (!repositoryPattern.MatchString(r.Repository) || r.Repository == "." || r.Repository == "..") {
+		errs = append(errs, field.Invalid(specPath.Child("repository"), r.Repository, "must be a GitHub repository name, without the owner"))
+	}
+
+	containersPath := specPath.Child("podTemplateSpec", "spec", "containers")
+	if len(r.PodTemplateSpec.Spec.Containers) == 0 {
+		errs = append(errs, field.Required(containersPath, "the runner pod needs at least one container"))
+	} else if r.needsRunnerContainer() {
+		runnerContainer := r.RunnerContainer
+		if runnerContainer == "" {
+			runnerContainer = "runner"
+		}
+		found := false
+		for _, container := range r.PodTemplateSpec.Spec.Containers {
+			found = found || container.Name == runnerContainer
+		}
+		if !found {
+			errs = append(errs, field.Invalid(specPath.Child("runnerContainer"), runnerContainer, "no container of podTemplateSpec has this name"))
+		}
+	}
+
+	if r.ReconciliationPeriod.Duration < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("reconciliationPeriod"), r.ReconciliationPeriod.Duration.String(), "must not be negative"))
+	}
+	if r.MinTTL.Duration < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("minTtl"), r.MinTTL.Duration.String(), "must not be negative"))
+	}
+	// the spec has no schedules of its own, the only timings that can conflict are the refresh margin and the lifetime
+	// of the registration token set by GitHub
+	if margin := r.RegistrationTokenRefreshMargin.Duration; margin < 0 || margin >= registrationTokenLifetime {
+		errs = append(errs, field.Invalid(specPath.Child("registrationTokenRefreshMargin"), margin.String(),
+			fmt.Sprintf("must be shorter than the %s lifetime of registration tokens", registrationTokenLifetime)))
+	}
+	if r.SharedCache != nil && r.SharedCache.RefreshInterval.Duration < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("sharedCache", "refreshInterval"), r.SharedCache.RefreshInterval.Duration.String(), "must not be negative"))
+	}
+
+	return errs
+}
+
+// needsRunnerContainer tells if the operator modifies the runner container
+func (r GithubActionRunnerSpec) needsRunnerContainer() bool {
+	return r.Registration.InjectEnv || r.Registration.Mode == InitContainerRegistration ||
+		(r.ContainerRuntime != "" && r.ContainerRuntime != NoContainerRuntime)
+}
+
+// validateCredentials checks that the referenced Kubernetes secrets and keys exist, other sources are not checked
+func (v *githubActionRunnerValidator) validateCredentials(ctx context.Context, runner *GithubActionRunner, old *GithubActionRunner, specPath *field.Path) (field.ErrorList, error) {
+	if runner.Spec.CredentialsSource != "" && runner.Spec.CredentialsSource != SecretSource {
+		return nil, nil
+	}
+	unchanged := old != nil && (old.Spec.CredentialsSource == "" || old.Spec.CredentialsSource == SecretSource)
+
+	var errs field.ErrorList
+	if ref := runner.Spec.TokenRef; ref.Name != "" && !(unchanged && old.Spec.TokenRef == ref) {
+		refErrs, err := v.validateSecretKeys(ctx, runner.Namespace, ref.Name, []string{ref.Key}, specPath.Child("tokenRef"))
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, refErrs...)
+	}
+	if ref := runner.Spec.AppSecretRef; ref != nil && !(unchanged && old.Spec.AppSecretRef != nil && *old.Spec.AppSecretRef == *ref) {
+		keys := []string{ref.IntegrationIDKey, ref.PrivateKeyKey}
+		refErrs, err := v.validateSecretKeys(ctx, runner.Namespace, ref.Name, keys, specPath.Child("appSecretRef"))
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, refErrs...)
+	}
+
+	return errs, nil
+}
+
+func (v *githubActionRunnerValidator) validateSecretKeys(ctx context.Context, namespace string, name string, keys []string, refPath *field.Path) (field.ErrorList, error) {
+	secret := &v1.Secret{}
+	if err := v.reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return field.ErrorList{field.NotFound(refPath.Child("name"), name)}, nil
+		}
+		return nil, err
+	}
+
+	var errs field.ErrorList
+	for _, key := range keys {
+		if _, found := secret.Data[key]; key != "" && !found {
+			errs = append(errs, field.Invalid(refPath, key, fmt.Sprintf("secret %s has no such key", name)))
+		}
+	}
+
+	return errs, nil
+}
+
+// validateUniquePool rejects pools whose runners cannot be told apart from the runners of another pool.
+// Runners are matched to pools by name prefix, so pools of the same organization and repository must not have names
+// prefixing each other, in any namespace.
+func (v *githubActionRunnerValidator) validateUniquePool(ctx context.Context, runner *GithubActionRunner, specPath *field.Path) (field.ErrorList, error) {
+	runnerList := &GithubActionRunnerList{}
+	if err := v.reader.List(ctx, runnerList); err != nil {
+		return nil, err
+	}
+
+	var errs field.ErrorList
+	for _, other := range runnerList.Items {
+		if other.Namespace == runner.Namespace && other.Name == runner.Name {
+			continue
+		}
+		if !strings.EqualFold(other.Spec.Organization, runner.Spec.Organization) || !strings.EqualFold(other.Spec.Repository, runner.Spec.Repository) {
+			continue
+		}
+		if strings.HasPrefix(other.Name, runner.Name) || strings.HasPrefix(runner.Name, other.Name) {
+			errs = append(errs, field.Duplicate(specPath.Child("organization"),
+				fmt.Sprintf("%s/%s already registers runners named %s-* at %s", other.Namespace, other.Name, other.Name, scope(other.Spec))))
+		}
+	}
+
+	return errs, nil
+}
+
+func scope(spec GithubActionRunnerSpec) string {
+	if spec.Repository == "" {
+		return spec.Organization
+	}
+	return spec.Organization + "/" + spec.Repository
+}
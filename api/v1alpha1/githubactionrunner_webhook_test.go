@@ -0,0 +1,190 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func validRunner() *GithubActionRunner {
+	return &GithubActionRunner{
+		ObjectMeta: metav1.ObjectMeta{Name: "somerunner", Namespace: "someNamespace"},
+		Spec: GithubActionRunnerSpec{
+			Organization: "someOrg",
+			MinRunners:   1,
+			MaxRunners:   2,
+			PodTemplateSpec: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{Containers: []v1.Container{{Name: "runner", Image: "someImage"}}},
+			},
+			TokenRef: v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: "someSecret"},
+				Key:                  "GH_TOKEN",
+			},
+			ReconciliationPeriod:           metav1.Duration{Duration: time.Minute},
+			RegistrationTokenRefreshMargin: metav1.Duration{Duration: 5 * time.Minute},
+		},
+	}
+}
+
+func TestValidateSpec(t *testing.T) {
+	tests := map[string]struct {
+		mutate func(spec *GithubActionRunnerSpec)
+		field  string
+	}{
+		"valid":                {mutate: func(spec *GithubActionRunnerSpec) {}},
+		"below minimum":        {mutate: func(spec *GithubActionRunnerSpec) { spec.MaxRunners = 0 }, field: "spec"},
+		"missing organization": {mutate: func(spec *GithubActionRunnerSpec) { spec.Organization = "" }, field: "spec.organization"},
+		"organization url":     {mutate: func(spec *GithubActionRunnerSpec) { spec.Organization = "github.com/someOrg" }, field: "spec.organization"},
+		"owner in repository":  {mutate: func(spec *GithubActionRunnerSpec) { spec.Repository = "someOrg/someRepo" }, field: "spec.repository"},
+		"repository":           {mutate: func(spec *GithubActionRunnerSpec) { spec.Repository = "some.Repo_1" }},
+		"no containers": {
+			mutate: func(spec *GithubActionRunnerSpec) { spec.PodTemplateSpec.Spec.Containers = nil },
+			field:  "spec.podTemplateSpec.spec.containers",
+		},
+		"missing runner container": {
+			mutate: func(spec *GithubActionRunnerSpec) {
+				spec.Registration.InjectEnv = true
+				spec.RunnerContainer = "other"
+			},
+			field: "spec.runnerContainer",
+		},
+		"unused runner container": {mutate: func(spec *GithubActionRunnerSpec) { spec.RunnerContainer = "other" }},
+		"refresh margin beyond token lifetime": {
+			mutate: func(spec *GithubActionRunnerSpec) { spec.RegistrationTokenRefreshMargin.Duration = time.Hour },
+			field:  "spec.registrationTokenRefreshMargin",
+		},
+		"negative reconciliation period": {
+			mutate: func(spec *GithubActionRunnerSpec) { spec.ReconciliationPeriod.Duration = -time.Minute },
+			field:  "spec.reconciliationPeriod",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			runner := validRunner()
+			test.mutate(&runner.Spec)
+			errs := runner.Spec.validate(field.NewPath("spec"))
+			if test.field == "" {
+				assert.Empty(t, errs)
+				return
+			}
+			if assert.Len(t, errs, 1) {
+				assert.Equal(t, test.field, errs[0].Field)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	runner := validRunner()
+	runner.Spec.PodTemplateSpec.Spec.Containers[0].Name = "someContainer"
+	runner.Spec.ReconciliationPeriod = metav1.Duration{}
+
+	assert.NoError(t, (&githubActionRunnerDefaulter{}).Default(context.TODO(), runner))
+	assert.Equal(t, "someContainer", runner.Spec.RunnerContainer)
+	assert.Equal(t, time.Minute, runner.Spec.ReconciliationPeriod.Duration)
+	assert.Equal(t, LeastRecent, runner.Spec.DeletionOrder)
+	assert.Equal(t, EnvironmentRegistration, runner.Spec.Registration.Mode)
+
+	runner.Spec.PodTemplateSpec.Spec.Containers = append(runner.Spec.PodTemplateSpec.Spec.Containers, v1.Container{Name: "sidecar"})
+	runner.Spec.RunnerContainer = ""
+	assert.NoError(t, (&githubActionRunnerDefaulter{}).Default(context.TODO(), runner))
+	assert.Equal(t, "runner", runner.Spec.RunnerContainer)
+}
+
+func TestValidateCluster(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, AddToScheme(scheme))
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "someSecret", Namespace: "someNamespace"},
+		Data:       map[string][]byte{"GH_TOKEN": []byte("someToken")},
+	}
+	existing := validRunner()
+	existing.Name = "pool"
+	existing.Namespace = "otherNamespace"
+	validator := &githubActionRunnerValidator{reader: fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret, existing).Build()}
+	ctx := context.TODO()
+
+	_, err := validator.ValidateCreate(ctx, validRunner())
+	assert.NoError(t, err)
+
+	missingSecret := validRunner()
+	missingSecret.Spec.TokenRef.Name = "otherSecret"
+	_, err = validator.ValidateCreate(ctx, missingSecret)
+	assert.True(t, apierrors.IsInvalid(err))
+	assert.ErrorContains(t, err, "spec.tokenRef.name: Not found")
+
+	missingKey := validRunner()
+	missingKey.Spec.TokenRef.Key = "OTHER_KEY"
+	_, err = validator.ValidateCreate(ctx, missingKey)
+	assert.ErrorContains(t, err, "secret someSecret has no such key")
+
+	// a secret removed after creation does not block updates leaving the reference alone
+	_, err = validator.ValidateUpdate(ctx, missingSecret, missingSecret)
+	assert.NoError(t, err)
+
+	// runners of pools with prefixing names cannot be told apart
+	duplicate := validRunner()
+	duplicate.Name = "pool-large"
+	duplicate.Spec.Organization = "SOMEORG"
+	_, err = validator.ValidateCreate(ctx, duplicate)
+	assert.ErrorContains(t, err, "otherNamespace/pool already registers runners")
+
+	// pools created before the check are still released by their finalizer
+	released := duplicate.DeepCopy()
+	released.Finalizers = []string{"someFinalizer"}
+	_, err = validator.ValidateUpdate(ctx, released, duplicate)
+	assert.NoError(t, err)
+
+	duplicate.Spec.Repository = "someRepo"
+	_, err = validator.ValidateCreate(ctx, duplicate)
+	assert.NoError(t, err)
+
+	_, err = validator.ValidateUpdate(ctx, existing, existing)
+	assert.NoError(t, err)
+}
+
+func TestValidateClass(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, AddToScheme(scheme))
+
+	class := &ClusterGithubActionRunnerClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "someClass"},
+		Spec: ClusterGithubActionRunnerClassSpec{
+			PodTemplateSpec: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{Containers: []v1.Container{{Name: "runner", Image: "someImage"}}},
+			},
+			TokenRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: "classSecret"},
+				Key:                  "GH_TOKEN",
+			},
+		},
+	}
+	validator := &githubActionRunnerValidator{reader: fake.NewClientBuilder().WithScheme(scheme).WithObjects(class).Build()}
+	ctx := context.TODO()
+
+	classRunner := validRunner()
+	classRunner.Spec.ClassName = "otherClass"
+	classRunner.Spec.PodTemplateSpec = v1.PodTemplateSpec{}
+	classRunner.Spec.TokenRef = v1.SecretKeySelector{}
+	_, err := validator.ValidateCreate(ctx, classRunner)
+	assert.ErrorContains(t, err, "spec.className: Not found")
+
+	// the pod template and credentials of the class are validated
+	classRunner.Spec.ClassName = "someClass"
+	_, err = validator.ValidateCreate(ctx, classRunner)
+	assert.ErrorContains(t, err, "spec.tokenRef.name: Not found: \"classSecret\"")
+	assert.NotContains(t, err.Error(), "containers")
+}
@@ -0,0 +1,147 @@
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// GithubActionRunnerInstanceSpec identifies the pool and pod a runner record belongs to
+type GithubActionRunnerInstanceSpec struct {
+	// Name of the GithubActionRunner pool the runner belongs to
+	// +kubebuilder:validation:Required
+	Pool string `json:"pool"`
+
+	// Name of the pod hosting the runner
+	// +kubebuilder:validation:Required
+	PodName string `json:"podName"`
+}
+
+const (
+	// RunnerPending is a runner whose pod exists but has not registered at GitHub yet.
+	RunnerPending RunnerPhase = "Pending"
+	// RunnerIdle is a registered runner waiting for a job.
+	RunnerIdle RunnerPhase = "Idle"
+	// RunnerBusy is a registered runner executing a job.
+	RunnerBusy RunnerPhase = "Busy"
+	// RunnerOffline is a registered runner GitHub reports as offline.
+	RunnerOffline RunnerPhase = "Offline"
+	// RunnerTerminating is a runner whose pod is being deleted.
+	RunnerTerminating RunnerPhase = "Terminating"
+)
+
+// RunnerPhase is a summary of the lifecycle state of a single runner.
+// +kubebuilder:validation:Enum=Pending;Idle;Busy;Offline;Terminating
+type RunnerPhase string
+
+// GithubActionRunnerInstanceStatus defines the observed state of a single runner
+type GithubActionRunnerInstanceStatus struct {
+	// Summary of the runner lifecycle state
+	// +optional
+	Phase RunnerPhase `json:"phase,omitempty"`
+
+	// ID of the runner at GitHub, set once registered
+	// +optional
+	RunnerID int64 `json:"runnerId,omitempty"`
+
+	// Name of the runner at GitHub
+	// +optional
+	RunnerName string `json:"runnerName,omitempty"`
+
+	// Labels of the runner as reported by GitHub
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+
+	// Whether GitHub reports the runner as online
+	Online bool `json:"online"`
+
+	// Whether GitHub reports the runner as executing a job
+	Busy bool `json:"busy"`
+
+	// URL of the job currently executed by the runner, when known
+	// +optional
+	JobURL string `json:"jobUrl,omitempty"`
+
+	// URL of the workflow run currently executed by the runner, when known
+	// +optional
+	RunURL string `json:"runUrl,omitempty"`
+
+	// When the pod hosting the runner was created
+	// +optional
+	PodCreatedAt *metav1.Time `json:"podCreatedAt,omitempty"`
+
+	// When the runner was first seen registered at GitHub
+	// +optional
+	RegisteredAt *metav1.Time `json:"registeredAt,omitempty"`
+
+	// When the runner was last seen picking up a job
+	// +optional
+	BusySince *metav1.Time `json:"busySince,omitempty"`
+
+	// When deletion of the pod hosting the runner was requested
+	// +optional
+	TerminatingAt *metav1.Time `json:"terminatingAt,omitempty"`
+
+	// The pool the runner registered with, to unregister the runner once the pool is gone
+	// +optional
+	Pool *PoolRecord `json:"pool,omitempty"`
+}
+
+// PoolRecord is what a runner instance records of its GithubActionRunner, with the spec merged with its class
+type PoolRecord struct {
+	// UID of the GithubActionRunner controlling the pod of the runner
+	UID types.UID `json:"uid"`
+
+	// Organization the runner registered at
+	Organization string `json:"organization"`
+
+	// Repository the runner registered at, empty for organization runners
+	// +optional
+	Repository string `json:"repository,omitempty"`
+
+	// Where the secrets referenced by tokenRef and appSecretRef are read from
+	// +optional
+	CredentialsSource CredentialsSource `json:"credentialsSource,omitempty"`
+
+	// PAT of the pool
+	// +optional
+	TokenRef *v1.SecretKeySelector `json:"tokenRef,omitempty"`
+
+	// GitHub App of the pool
+	// +optional
+	AppSecretRef *GithubAppSecretRef `json:"appSecretRef,omitempty"`
+
+	// GitHub App installation of the pool
+	// +optional
+	InstallationID int64 `json:"installationId,omitempty"`
+}
+
+// GithubActionRunnerInstance records the state of a single runner in a GithubActionRunner pool
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=githubactionrunnerinstances,scope=Namespaced,shortName=gari
+// +kubebuilder:printcolumn:name="Pool",type=string,JSONPath=`.spec.pool`
+// +kubebuilder:printcolumn:name="RunnerID",type=integer,JSONPath=`.status.runnerId`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +operator-sdk:csv:customresourcedefinitions:displayName="GitHub Actions Runner Instance"
+type GithubActionRunnerInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GithubActionRunnerInstanceSpec   `json:"spec,omitempty"`
+	Status GithubActionRunnerInstanceStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// GithubActionRunnerInstanceList contains a list of GithubActionRunnerInstance
+type GithubActionRunnerInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubActionRunnerInstance `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubActionRunnerInstance{}, &GithubActionRunnerInstanceList{})
+}
@@ -0,0 +1,47 @@
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GithubActionRunnerQuotaSpec caps the runners of all GithubActionRunner pools in the namespace of the quota
+type GithubActionRunnerQuotaSpec struct {
+	// Maximum number of runner pods across all pools in the namespace
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Maximum Runners",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:podCount"}
+	MaxRunners *int `json:"maxRunners,omitempty"`
+
+	// Maximum sum of the resource requests of runner pods across all pools in the namespace, like cpu and memory.
+	// Containers without requests count with their limits.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Requests"
+	Requests v1.ResourceList `json:"requests,omitempty"`
+}
+
+// GithubActionRunnerQuota is the Schema for the githubactionrunnerquotas API.
+// All quotas in a namespace are enforced when pools scale up, pools exceeding one are throttled.
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=githubactionrunnerquotas,scope=Namespaced,shortName=garq
+// +kubebuilder:printcolumn:name="maxRunners",type=integer,JSONPath=`.spec.maxRunners`
+// +operator-sdk:csv:customresourcedefinitions:displayName="GitHub Actions Runner Quota"
+type GithubActionRunnerQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GithubActionRunnerQuotaSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// GithubActionRunnerQuotaList contains a list of GithubActionRunnerQuota
+type GithubActionRunnerQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubActionRunnerQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubActionRunnerQuota{}, &GithubActionRunnerQuotaList{})
+}
@@ -0,0 +1,173 @@
+package v1alpha1
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// These tests use Ginkgo (BDD-style Go testing framework). Refer to
+// http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
+
+var k8sClient client.Client
+var testEnv *envtest.Environment
+var cancel context.CancelFunc
+
+func TestWebhooks(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Webhook Suite")
+}
+
+var _ = BeforeSuite(func() {
+	logf.SetLogger(zap.New(zap.WriteTo(GinkgoWriter)))
+
+	By("bootstrapping test environment")
+	testEnv = &envtest.Environment{
+		CRDDirectoryPaths: []string{filepath.Join("..", "..", "config", "crd", "bases")},
+		WebhookInstallOptions: envtest.WebhookInstallOptions{
+			Paths: []string{filepath.Join("..", "..", "config", "webhook")},
+		},
+	}
+
+	cfg, err := testEnv.Start()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(cfg).ToNot(BeNil())
+
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(AddToScheme(scheme)).To(Succeed())
+
+	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme})
+	Expect(err).ToNot(HaveOccurred())
+
+	webhookOptions := &testEnv.WebhookInstallOptions
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:             scheme,
+		MetricsBindAddress: "0",
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Host:    webhookOptions.LocalServingHost,
+			Port:    webhookOptions.LocalServingPort,
+			CertDir: webhookOptions.LocalServingCertDir,
+		}),
+	})
+	Expect(err).ToNot(HaveOccurred())
+	Expect((&GithubActionRunner{}).SetupWebhookWithManager(mgr)).To(Succeed())
+
+	var ctx context.Context
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		defer GinkgoRecover()
+		Expect(mgr.Start(ctx)).To(Succeed())
+	}()
+
+	// wait for the webhook server to serve
+	address := fmt.Sprintf("%s:%d", webhookOptions.LocalServingHost, webhookOptions.LocalServingPort)
+	Eventually(func() error {
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: time.Second}, "tcp", address, &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}).Should(Succeed())
+}, 60)
+
+var _ = AfterSuite(func() {
+	By("tearing down the test environment")
+	cancel()
+	err := testEnv.Stop()
+	Expect(err).ToNot(HaveOccurred())
+})
+
+var _ = Describe("GithubActionRunner webhooks", func() {
+	ctx := context.Background()
+	const namespace = "default"
+
+	newRunner := func(name string) *GithubActionRunner {
+		runner := validRunner()
+		runner.Name = name
+		runner.Namespace = namespace
+		runner.Spec.TokenRef.Name = "webhook-token"
+		return runner
+	}
+
+	BeforeEach(func() {
+		secret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "webhook-token", Namespace: namespace},
+			Data:       map[string][]byte{"GH_TOKEN": []byte("someToken")},
+		}
+		err := k8sClient.Create(ctx, secret)
+		Expect(err == nil || apierrors.IsAlreadyExists(err)).To(BeTrue())
+	})
+
+	It("defaults the runner container", func() {
+		runner := newRunner("defaulted")
+		runner.Spec.PodTemplateSpec.Spec.Containers[0].Name = "someContainer"
+		Expect(k8sClient.Create(ctx, runner)).To(Succeed())
+		Expect(runner.Spec.RunnerContainer).To(Equal("someContainer"))
+		Expect(k8sClient.Delete(ctx, runner)).To(Succeed())
+	})
+
+	It("rejects pod templates without containers", func() {
+		runner := newRunner("no-containers")
+		runner.Spec.PodTemplateSpec.Spec.Containers = nil
+		err := k8sClient.Create(ctx, runner)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.podTemplateSpec.spec.containers"))
+	})
+
+	It("rejects invalid scopes", func() {
+		runner := newRunner("bad-scope")
+		runner.Spec.Repository = "someOrg/someRepo"
+		err := k8sClient.Create(ctx, runner)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.repository"))
+	})
+
+	It("rejects missing token secrets", func() {
+		runner := newRunner("missing-secret")
+		runner.Spec.TokenRef.Name = "missing"
+		err := k8sClient.Create(ctx, runner)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.tokenRef.name"))
+	})
+
+	It("rejects refresh margins beyond the token lifetime", func() {
+		runner := newRunner("bad-margin")
+		runner.Spec.RegistrationTokenRefreshMargin = metav1.Duration{Duration: 2 * time.Hour}
+		err := k8sClient.Create(ctx, runner)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.registrationTokenRefreshMargin"))
+	})
+
+	It("rejects pools overlapping with existing pools", func() {
+		pool := newRunner("pool")
+		Expect(k8sClient.Create(ctx, pool)).To(Succeed())
+
+		overlapping := newRunner("pool-large")
+		err := k8sClient.Create(ctx, overlapping)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("default/pool already registers runners"))
+
+		overlapping.Spec.Repository = "someRepo"
+		Expect(k8sClient.Create(ctx, overlapping)).To(Succeed())
+	})
+})
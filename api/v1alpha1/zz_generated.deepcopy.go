@@ -22,10 +22,95 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterGithubActionRunnerClass) DeepCopyInto(out *ClusterGithubActionRunnerClass) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterGithubActionRunnerClass.
+func (in *ClusterGithubActionRunnerClass) DeepCopy() *ClusterGithubActionRunnerClass {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterGithubActionRunnerClass)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterGithubActionRunnerClass) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterGithubActionRunnerClassList) DeepCopyInto(out *ClusterGithubActionRunnerClassList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterGithubActionRunnerClass, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterGithubActionRunnerClassList.
+func (in *ClusterGithubActionRunnerClassList) DeepCopy() *ClusterGithubActionRunnerClassList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterGithubActionRunnerClassList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterGithubActionRunnerClassList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterGithubActionRunnerClassSpec) DeepCopyInto(out *ClusterGithubActionRunnerClassSpec) {
+	*out = *in
+	in.PodTemplateSpec.DeepCopyInto(&out.PodTemplateSpec)
+	if in.TokenRef != nil {
+		in, out := &in.TokenRef, &out.TokenRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AppSecretRef != nil {
+		in, out := &in.AppSecretRef, &out.AppSecretRef
+		*out = new(GithubAppSecretRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterGithubActionRunnerClassSpec.
+func (in *ClusterGithubActionRunnerClassSpec) DeepCopy() *ClusterGithubActionRunnerClassSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterGithubActionRunnerClassSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GithubActionRunner) DeepCopyInto(out *GithubActionRunner) {
 	*out = *in
@@ -53,6 +138,121 @@ func (in *GithubActionRunner) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubActionRunnerInstance) DeepCopyInto(out *GithubActionRunnerInstance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubActionRunnerInstance.
+func (in *GithubActionRunnerInstance) DeepCopy() *GithubActionRunnerInstance {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubActionRunnerInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GithubActionRunnerInstance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubActionRunnerInstanceList) DeepCopyInto(out *GithubActionRunnerInstanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GithubActionRunnerInstance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubActionRunnerInstanceList.
+func (in *GithubActionRunnerInstanceList) DeepCopy() *GithubActionRunnerInstanceList {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubActionRunnerInstanceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GithubActionRunnerInstanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubActionRunnerInstanceSpec) DeepCopyInto(out *GithubActionRunnerInstanceSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubActionRunnerInstanceSpec.
+func (in *GithubActionRunnerInstanceSpec) DeepCopy() *GithubActionRunnerInstanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubActionRunnerInstanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubActionRunnerInstanceStatus) DeepCopyInto(out *GithubActionRunnerInstanceStatus) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodCreatedAt != nil {
+		in, out := &in.PodCreatedAt, &out.PodCreatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.RegisteredAt != nil {
+		in, out := &in.RegisteredAt, &out.RegisteredAt
+		*out = (*in).DeepCopy()
+	}
+	if in.BusySince != nil {
+		in, out := &in.BusySince, &out.BusySince
+		*out = (*in).DeepCopy()
+	}
+	if in.TerminatingAt != nil {
+		in, out := &in.TerminatingAt, &out.TerminatingAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Pool != nil {
+		in, out := &in.Pool, &out.Pool
+		*out = new(PoolRecord)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubActionRunnerInstanceStatus.
+func (in *GithubActionRunnerInstanceStatus) DeepCopy() *GithubActionRunnerInstanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubActionRunnerInstanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GithubActionRunnerList) DeepCopyInto(out *GithubActionRunnerList) {
 	*out = *in
@@ -85,13 +285,117 @@ func (in *GithubActionRunnerList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubActionRunnerQuota) DeepCopyInto(out *GithubActionRunnerQuota) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubActionRunnerQuota.
+func (in *GithubActionRunnerQuota) DeepCopy() *GithubActionRunnerQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubActionRunnerQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GithubActionRunnerQuota) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubActionRunnerQuotaList) DeepCopyInto(out *GithubActionRunnerQuotaList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GithubActionRunnerQuota, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubActionRunnerQuotaList.
+func (in *GithubActionRunnerQuotaList) DeepCopy() *GithubActionRunnerQuotaList {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubActionRunnerQuotaList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GithubActionRunnerQuotaList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubActionRunnerQuotaSpec) DeepCopyInto(out *GithubActionRunnerQuotaSpec) {
+	*out = *in
+	if in.MaxRunners != nil {
+		in, out := &in.MaxRunners, &out.MaxRunners
+		*out = new(int)
+		**out = **in
+	}
+	if in.Requests != nil {
+		in, out := &in.Requests, &out.Requests
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubActionRunnerQuotaSpec.
+func (in *GithubActionRunnerQuotaSpec) DeepCopy() *GithubActionRunnerQuotaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubActionRunnerQuotaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GithubActionRunnerSpec) DeepCopyInto(out *GithubActionRunnerSpec) {
 	*out = *in
 	out.MinTTL = in.MinTTL
 	in.PodTemplateSpec.DeepCopyInto(&out.PodTemplateSpec)
+	if in.VolumeClaimTemplates != nil {
+		in, out := &in.VolumeClaimTemplates, &out.VolumeClaimTemplates
+		*out = make([]v1.PersistentVolumeClaim, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SharedCache != nil {
+		in, out := &in.SharedCache, &out.SharedCache
+		*out = new(SharedCacheSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Registration.DeepCopyInto(&out.Registration)
 	in.TokenRef.DeepCopyInto(&out.TokenRef)
+	if in.AppSecretRef != nil {
+		in, out := &in.AppSecretRef, &out.AppSecretRef
+		*out = new(GithubAppSecretRef)
+		**out = **in
+	}
 	out.ReconciliationPeriod = in.ReconciliationPeriod
+	out.RegistrationTokenRefreshMargin = in.RegistrationTokenRefreshMargin
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubActionRunnerSpec.
@@ -107,9 +411,18 @@ func (in *GithubActionRunnerSpec) DeepCopy() *GithubActionRunnerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GithubActionRunnerStatus) DeepCopyInto(out *GithubActionRunnerStatus) {
 	*out = *in
+	if in.RegistrationTokenExpiresAt != nil {
+		in, out := &in.RegistrationTokenExpiresAt, &out.RegistrationTokenExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.SharedCache != nil {
+		in, out := &in.SharedCache, &out.SharedCache
+		*out = new(SharedCacheStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -125,3 +438,100 @@ func (in *GithubActionRunnerStatus) DeepCopy() *GithubActionRunnerStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubAppSecretRef) DeepCopyInto(out *GithubAppSecretRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubAppSecretRef.
+func (in *GithubAppSecretRef) DeepCopy() *GithubAppSecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubAppSecretRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PoolRecord) DeepCopyInto(out *PoolRecord) {
+	*out = *in
+	if in.TokenRef != nil {
+		in, out := &in.TokenRef, &out.TokenRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AppSecretRef != nil {
+		in, out := &in.AppSecretRef, &out.AppSecretRef
+		*out = new(GithubAppSecretRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PoolRecord.
+func (in *PoolRecord) DeepCopy() *PoolRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(PoolRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistrationSpec) DeepCopyInto(out *RegistrationSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistrationSpec.
+func (in *RegistrationSpec) DeepCopy() *RegistrationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistrationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedCacheSpec) DeepCopyInto(out *SharedCacheSpec) {
+	*out = *in
+	in.VolumeClaimSpec.DeepCopyInto(&out.VolumeClaimSpec)
+	in.WarmUp.DeepCopyInto(&out.WarmUp)
+	out.RefreshInterval = in.RefreshInterval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedCacheSpec.
+func (in *SharedCacheSpec) DeepCopy() *SharedCacheSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedCacheSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedCacheStatus) DeepCopyInto(out *SharedCacheStatus) {
+	*out = *in
+	if in.LastWarmUpTime != nil {
+		in, out := &in.LastWarmUpTime, &out.LastWarmUpTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedCacheStatus.
+func (in *SharedCacheStatus) DeepCopy() *SharedCacheStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedCacheStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,161 @@
+package v1beta1
+
+import (
+	"github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// ConvertTo converts this GithubActionRunner to the v1alpha1 hub version
+func (r *GithubActionRunner) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.GithubActionRunner)
+	src := r.DeepCopy()
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1alpha1.GithubActionRunnerSpec{
+		Organization:                   src.Spec.Scope.Organization,
+		Repository:                     src.Spec.Scope.Repository,
+		MinRunners:                     src.Spec.Scaling.MinRunners,
+		MaxRunners:                     src.Spec.Scaling.MaxRunners,
+		MinTTL:                         src.Spec.Scaling.MinTTL,
+		ClassName:                      src.Spec.ClassName,
+		Paused:                         src.Spec.Paused,
+		Drain:                          src.Spec.Drain,
+		PodTemplateSpec:                src.Spec.PodTemplateSpec,
+		PodNaming:                      v1alpha1.PodNaming(src.Spec.PodNaming),
+		VolumeClaimTemplates:           src.Spec.VolumeClaimTemplates,
+		RunnerContainer:                src.Spec.RunnerContainer,
+		ContainerRuntime:               v1alpha1.ContainerRuntime(src.Spec.ContainerRuntime),
+		CredentialsSource:              v1alpha1.CredentialsSource(src.Spec.Credentials.Source),
+		InstallationID:                 src.Spec.Credentials.InstallationID,
+		ReconciliationPeriod:           src.Spec.Scaling.ReconciliationPeriod,
+		RegistrationTokenRefreshMargin: src.Spec.Registration.TokenRefreshMargin,
+		Priority:                       src.Spec.Scaling.Priority,
+		DeletionOrder:                  v1alpha1.SortOrder(src.Spec.Scaling.DeletionOrder),
+		Registration: v1alpha1.RegistrationSpec{
+			Mode:       v1alpha1.RegistrationMode(src.Spec.Registration.Mode),
+			RunnerHome: src.Spec.Registration.RunnerHome,
+			InjectEnv:  src.Spec.Registration.InjectEnv,
+			Labels:     src.Spec.Registration.Labels,
+			Group:      src.Spec.Registration.Group,
+			WorkDir:    src.Spec.Registration.WorkDir,
+		},
+	}
+	if src.Spec.Credentials.TokenRef != nil {
+		dst.Spec.TokenRef = *src.Spec.Credentials.TokenRef
+	}
+	if ref := src.Spec.Credentials.AppSecretRef; ref != nil {
+		dst.Spec.AppSecretRef = &v1alpha1.GithubAppSecretRef{
+			Name:              ref.Name,
+			IntegrationIDKey:  ref.IntegrationIDKey,
+			PrivateKeyKey:     ref.PrivateKeyKey,
+			InstallationIDKey: ref.InstallationIDKey,
+		}
+	}
+	if cache := src.Spec.SharedCache; cache != nil {
+		dst.Spec.SharedCache = &v1alpha1.SharedCacheSpec{
+			Name:            cache.Name,
+			VolumeClaimSpec: cache.VolumeClaimSpec,
+			WarmUp:          cache.WarmUp,
+			RefreshInterval: cache.RefreshInterval,
+		}
+	}
+
+	dst.Status = v1alpha1.GithubActionRunnerStatus{
+		CurrentSize:                src.Status.CurrentSize,
+		RegistrationTokenExpiresAt: src.Status.RegistrationTokenExpiresAt,
+		Conditions:                 src.Status.Conditions,
+	}
+	if cache := src.Status.SharedCache; cache != nil {
+		dst.Status.SharedCache = &v1alpha1.SharedCacheStatus{
+			Generation:     cache.Generation,
+			Current:        cache.Current,
+			Pending:        cache.Pending,
+			LastWarmUpTime: cache.LastWarmUpTime,
+		}
+	}
+
+	return nil
+}
+
+// ConvertFrom converts the v1alpha1 hub version to this GithubActionRunner
+func (r *GithubActionRunner) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.GithubActionRunner).DeepCopy()
+
+	r.ObjectMeta = src.ObjectMeta
+	r.Spec = GithubActionRunnerSpec{
+		Scope: ScopeSpec{
+			Organization: src.Spec.Organization,
+			Repository:   src.Spec.Repository,
+		},
+		Credentials: CredentialsSpec{
+			Source:         CredentialsSource(src.Spec.CredentialsSource),
+			InstallationID: src.Spec.InstallationID,
+		},
+		Scaling: ScalingPolicy{
+			MinRunners:           src.Spec.MinRunners,
+			MaxRunners:           src.Spec.MaxRunners,
+			MinTTL:               src.Spec.MinTTL,
+			DeletionOrder:        SortOrder(src.Spec.DeletionOrder),
+			ReconciliationPeriod: src.Spec.ReconciliationPeriod,
+			Priority:             src.Spec.Priority,
+		},
+		ClassName:            src.Spec.ClassName,
+		Paused:               src.Spec.Paused,
+		Drain:                src.Spec.Drain,
+		PodTemplateSpec:      src.Spec.PodTemplateSpec,
+		PodNaming:            PodNaming(src.Spec.PodNaming),
+		VolumeClaimTemplates: src.Spec.VolumeClaimTemplates,
+		RunnerContainer:      src.Spec.RunnerContainer,
+		ContainerRuntime:     ContainerRuntime(src.Spec.ContainerRuntime),
+		Registration: RegistrationSpec{
+			Mode:               RegistrationMode(src.Spec.Registration.Mode),
+			RunnerHome:         src.Spec.Registration.RunnerHome,
+			InjectEnv:          src.Spec.Registration.InjectEnv,
+			Labels:             src.Spec.Registration.Labels,
+			Group:              src.Spec.Registration.Group,
+			WorkDir:            src.Spec.Registration.WorkDir,
+			TokenRefreshMargin: src.Spec.RegistrationTokenRefreshMargin,
+		},
+	}
+	// an empty tokenRef of v1alpha1 means no token
+	if !isEmptySecretKeySelector(src.Spec.TokenRef) {
+		r.Spec.Credentials.TokenRef = &src.Spec.TokenRef
+	}
+	if ref := src.Spec.AppSecretRef; ref != nil {
+		r.Spec.Credentials.AppSecretRef = &GithubAppSecretRef{
+			Name:              ref.Name,
+			IntegrationIDKey:  ref.IntegrationIDKey,
+			PrivateKeyKey:     ref.PrivateKeyKey,
+			InstallationIDKey: ref.InstallationIDKey,
+		}
+	}
+	if cache := src.Spec.SharedCache; cache != nil {
+		r.Spec.SharedCache = &SharedCacheSpec{
+			Name:            cache.Name,
+			VolumeClaimSpec: cache.VolumeClaimSpec,
+			WarmUp:          cache.WarmUp,
+			RefreshInterval: cache.RefreshInterval,
+		}
+	}
+
+	r.Status = GithubActionRunnerStatus{
+		CurrentSize:                src.Status.CurrentSize,
+		RegistrationTokenExpiresAt: src.Status.RegistrationTokenExpiresAt,
+		Conditions:                 src.Status.Conditions,
+	}
+	if cache := src.Status.SharedCache; cache != nil {
+		r.Status.SharedCache = &SharedCacheStatus{
+			Generation:     cache.Generation,
+			Current:        cache.Current,
+			Pending:        cache.Pending,
+			LastWarmUpTime: cache.LastWarmUpTime,
+		}
+	}
+
+	return nil
+}
+
+func isEmptySecretKeySelector(ref v1.SecretKeySelector) bool {
+	return ref.Name == "" && ref.Key == "" && ref.Optional == nil
+}
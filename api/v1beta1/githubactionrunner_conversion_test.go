@@ -0,0 +1,101 @@
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	fuzz "github.com/google/gofuzz"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const fuzzIterations = 200
+
+func newFuzzer(seed int64) *fuzz.Fuzzer {
+	return fuzz.NewWithSeed(seed).NilChance(0.3).NumElements(0, 2).MaxDepth(8)
+}
+
+func TestConvert(t *testing.T) {
+	src := &GithubActionRunner{
+		ObjectMeta: metav1.ObjectMeta{Name: "somerunner", Namespace: "someNamespace"},
+		Spec: GithubActionRunnerSpec{
+			Scope: ScopeSpec{Organization: "someOrg", Repository: "someRepo"},
+			Credentials: CredentialsSpec{
+				Source: SecretSource,
+				TokenRef: &v1.SecretKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{Name: "someSecret"},
+					Key:                  "GH_TOKEN",
+				},
+			},
+			Scaling: ScalingPolicy{
+				MinRunners:           1,
+				MaxRunners:           3,
+				DeletionOrder:        MostRecent,
+				ReconciliationPeriod: metav1.Duration{Duration: time.Minute},
+			},
+			Registration: RegistrationSpec{
+				Mode:               InitContainerRegistration,
+				TokenRefreshMargin: metav1.Duration{Duration: 10 * time.Minute},
+			},
+		},
+	}
+
+	dst := &v1alpha1.GithubActionRunner{}
+	assert.NoError(t, src.ConvertTo(dst))
+	assert.Equal(t, "somerunner", dst.Name)
+	assert.Equal(t, "someOrg", dst.Spec.Organization)
+	assert.Equal(t, "someRepo", dst.Spec.Repository)
+	assert.Equal(t, "someSecret", dst.Spec.TokenRef.Name)
+	assert.Equal(t, v1alpha1.SecretSource, dst.Spec.CredentialsSource)
+	assert.Equal(t, 3, dst.Spec.MaxRunners)
+	assert.Equal(t, v1alpha1.MostRecent, dst.Spec.DeletionOrder)
+	assert.Equal(t, time.Minute, dst.Spec.ReconciliationPeriod.Duration)
+	assert.Equal(t, 10*time.Minute, dst.Spec.RegistrationTokenRefreshMargin.Duration)
+	assert.Equal(t, v1alpha1.InitContainerRegistration, dst.Spec.Registration.Mode)
+
+	dst.Spec.TokenRef = v1.SecretKeySelector{}
+	back := &GithubActionRunner{}
+	assert.NoError(t, back.ConvertFrom(dst))
+	assert.Nil(t, back.Spec.Credentials.TokenRef, "an empty tokenRef is no token")
+}
+
+func TestHubRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < fuzzIterations; seed++ {
+		hub := &v1alpha1.GithubActionRunner{}
+		newFuzzer(seed).Fuzz(hub)
+		hub.TypeMeta = metav1.TypeMeta{}
+
+		spoke := &GithubActionRunner{}
+		assert.NoError(t, spoke.ConvertFrom(hub))
+		roundTripped := &v1alpha1.GithubActionRunner{}
+		assert.NoError(t, spoke.ConvertTo(roundTripped))
+
+		if !apiequality.Semantic.DeepEqual(hub, roundTripped) {
+			t.Errorf("seed %d: v1alpha1 changed by a round trip through v1beta1: %#v != %#v", seed, hub.Spec, roundTripped.Spec)
+		}
+	}
+}
+
+func TestSpokeRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < fuzzIterations; seed++ {
+		spoke := &GithubActionRunner{}
+		newFuzzer(seed).Fuzz(spoke)
+		spoke.TypeMeta = metav1.TypeMeta{}
+		// not representable in v1alpha1, where an empty tokenRef means no token
+		if ref := spoke.Spec.Credentials.TokenRef; ref != nil && isEmptySecretKeySelector(*ref) {
+			spoke.Spec.Credentials.TokenRef = nil
+		}
+
+		hub := &v1alpha1.GithubActionRunner{}
+		assert.NoError(t, spoke.ConvertTo(hub))
+		roundTripped := &GithubActionRunner{}
+		assert.NoError(t, roundTripped.ConvertFrom(hub))
+
+		if !apiequality.Semantic.DeepEqual(spoke, roundTripped) {
+			t.Errorf("seed %d: v1beta1 changed by a round trip through v1alpha1: %#v != %#v", seed, spoke.Spec, roundTripped.Spec)
+		}
+	}
+}
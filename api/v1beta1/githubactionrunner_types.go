@@ -0,0 +1,360 @@
+package v1beta1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GithubActionRunnerSpec defines the desired state of GithubActionRunner
+type GithubActionRunnerSpec struct {
+	// Where the runners are registered at GitHub
+	// +kubebuilder:validation:Required
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Scope"
+	Scope ScopeSpec `json:"scope"`
+
+	// How the operator authenticates at GitHub to un/register runners
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default={}
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Credentials"
+	Credentials CredentialsSpec `json:"credentials,omitempty"`
+
+	// Size of the pool and how it shrinks
+	// +kubebuilder:validation:Required
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Scaling Policy"
+	Scaling ScalingPolicy `json:"scaling"`
+
+	// Name of a ClusterGithubActionRunnerClass to base the pool on. Its pod template is overridden by podTemplateSpec,
+	// its credentials and container runtime are used unless set here.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Class Name",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:text"}
+	ClassName string `json:"className,omitempty"`
+
+	// Suspends all scaling of the pool and calls to GitHub for it, existing runners are left alone
+	// +kubebuilder:validation:Optional
+	Paused bool `json:"paused,omitempty"`
+
+	// Stops the pool from taking new jobs by removing its idle runners, busy runners are removed once they finished
+	// their job until the pool is scaled to zero
+	// +kubebuilder:validation:Optional
+	Drain bool `json:"drain,omitempty"`
+
+	// Pod template of the runners, required unless className is set
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Pod Template"
+	PodTemplateSpec v1.PodTemplateSpec `json:"podTemplateSpec,omitempty"`
+
+	// How runner pods, and with them the runners at GitHub, are named. Ordinal names pods <name>-<slot> filling the lowest free slot.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="Generated"
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Pod Naming",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:select:Generated","urn:alm:descriptor:com.tectonic.ui:select:Ordinal"}
+	PodNaming PodNaming `json:"podNaming,omitempty"`
+
+	// Claims created for every runner slot, mounted by containers in podTemplateSpec like volumes of the same name.
+	// A claim is kept when its runner pod is replaced and deleted when the pool shrinks below its slot.
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Volume Claim Templates"
+	VolumeClaimTemplates []v1.PersistentVolumeClaim `json:"volumeClaimTemplates,omitempty"`
+
+	// Cache shared read-only by all runners of the pool, populated periodically by a warm-up job
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Shared Cache"
+	SharedCache *SharedCacheSpec `json:"sharedCache,omitempty"`
+
+	// Name of the container in podTemplateSpec running the GitHub Actions runner, defaults to the only container of
+	// podTemplateSpec, or runner
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Runner Container",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:text"}
+	RunnerContainer string `json:"runnerContainer,omitempty"`
+
+	// How runners register at GitHub
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default={}
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Registration"
+	Registration RegistrationSpec `json:"registration,omitempty"`
+
+	// Container runtime sidecar added to runner pods for jobs building containers
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="None"
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Container Runtime",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:select:None","urn:alm:descriptor:com.tectonic.ui:select:Dind","urn:alm:descriptor:com.tectonic.ui:select:RootlessDind","urn:alm:descriptor:com.tectonic.ui:select:BuildKit"}
+	ContainerRuntime ContainerRuntime `json:"containerRuntime,omitempty"`
+}
+
+// ScopeSpec is the organization or repository runners are registered at
+type ScopeSpec struct {
+	// Your GitHub organization
+	// +kubebuilder:validation:Required
+	Organization string `json:"organization"`
+
+	// Optional Github repository name, if repo scoped.
+	// +kubebuilder:validation:Optional
+	Repository string `json:"repository,omitempty"`
+}
+
+// CredentialsSpec selects the GitHub credentials used for the pool.
+// Without tokenRef and appSecretRef the GitHub App the operator is configured with is used.
+type CredentialsSpec struct {
+	// Where the secrets referenced by tokenRef and appSecretRef are read from. Vault and File need to be configured on the operator.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="Secret"
+	Source CredentialsSource `json:"source,omitempty"`
+
+	// PAT to un/register runners. Cannot be combined with appSecretRef.
+	// +kubebuilder:validation:Optional
+	TokenRef *v1.SecretKeySelector `json:"tokenRef,omitempty"`
+
+	// GitHub App to un/register runners, instead of the app the operator is configured with. Cannot be combined with tokenRef.
+	// +kubebuilder:validation:Optional
+	AppSecretRef *GithubAppSecretRef `json:"appSecretRef,omitempty"`
+
+	// GitHub App installation to use, looked up by organization if not set. Takes precedence over an installation ID in appSecretRef.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	InstallationID int64 `json:"installationId,omitempty"`
+}
+
+// ScalingPolicy bounds the size of the pool and controls how it shrinks
+type ScalingPolicy struct {
+	// Minimum pool-size. Note that you need one runner in order for jobs to be schedulable, else they fail claiming no runners match the selector labels.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=1
+	MinRunners int `json:"minRunners"`
+
+	// Maximum pool-size. Must be greater or equal to minRunners
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Required
+	MaxRunners int `json:"maxRunners"`
+
+	// Minimum time to live for a runner. This can avoid trashing by keeping pods around longer than required by jobs, keeping caches hot.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="0m"
+	MinTTL metav1.Duration `json:"minTtl,omitempty"`
+
+	// What order to delete idle pods in
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="LeastRecent"
+	DeletionOrder SortOrder `json:"deletionOrder,omitempty"`
+
+	// How often to reconcile/check the runner pool
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="1m"
+	ReconciliationPeriod metav1.Duration `json:"reconciliationPeriod,omitempty"`
+
+	// Priority of the pool over other pools in the namespace. When a GithubActionRunnerQuota throttles the pool, or
+	// pods of the pool cannot be scheduled, idle runners of pools in the namespace with a lower priority are reclaimed
+	// to make room, and those pools wait for this pool to scale.
+	// +kubebuilder:validation:Optional
+	Priority int32 `json:"priority,omitempty"`
+}
+
+// RegistrationMode selects where the runner registers with the registration token
+// +kubebuilder:validation:Enum=Environment;InitContainer
+type RegistrationMode string
+
+const (
+	// EnvironmentRegistration leaves registration to the runner container, which gets the token from the <name>-regtoken secret.
+	EnvironmentRegistration RegistrationMode = "Environment"
+	// InitContainerRegistration registers in an init container and hands only the resulting runner configuration to the runner container.
+	InitContainerRegistration RegistrationMode = "InitContainer"
+)
+
+// RegistrationSpec configures how runners register at GitHub
+type RegistrationSpec struct {
+	// Where to register, InitContainer keeps the registration token out of the environment of jobs
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="Environment"
+	Mode RegistrationMode `json:"mode,omitempty"`
+
+	// Directory the runner is installed in, in the image of the runner container
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="/home/runner"
+	RunnerHome string `json:"runnerHome,omitempty"`
+
+	// Inject the organization, repository, runner name, labels, group, work directory and registration token into the
+	// environment of the runner container, variables already set in podTemplateSpec are kept
+	// +kubebuilder:validation:Optional
+	InjectEnv bool `json:"injectEnv,omitempty"`
+
+	// Custom labels of the runners, in addition to the default labels added by GitHub
+	// +kubebuilder:validation:Optional
+	Labels []string `json:"labels,omitempty"`
+
+	// Runner group to register the runners in, organization runners only
+	// +kubebuilder:validation:Optional
+	Group string `json:"group,omitempty"`
+
+	// Work directory of the runners, relative to the runner home
+	// +kubebuilder:validation:Optional
+	WorkDir string `json:"workDir,omitempty"`
+
+	// How long before the registration token expires to refresh it
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="5m"
+	TokenRefreshMargin metav1.Duration `json:"tokenRefreshMargin,omitempty"`
+}
+
+// SharedCacheSpec configures a cache volume shared by all runners of a pool.
+// Every refresh populates a new claim, runner pods created after the warm-up job succeeded mount the new claim.
+type SharedCacheSpec struct {
+	// Name of the volume, used in volumeMounts of podTemplateSpec and the warm-up pod
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Claim created for every generation of the cache. Its access modes must allow the warm-up pod to write to it
+	// and all runner pods to mount it read-only afterwards, like ReadWriteMany.
+	// +kubebuilder:validation:Required
+	VolumeClaimSpec v1.PersistentVolumeClaimSpec `json:"volumeClaimSpec"`
+
+	// Pod of the job populating a new generation of the cache, it gets the volume mounted read-write
+	// +kubebuilder:validation:Required
+	WarmUp v1.PodTemplateSpec `json:"warmUp"`
+
+	// How often to populate a new generation of the cache
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="24h"
+	RefreshInterval metav1.Duration `json:"refreshInterval,omitempty"`
+}
+
+// SharedCacheStatus records the generations of the shared cache
+type SharedCacheStatus struct {
+	// number of generations started, used to name their claims
+	// +optional
+	Generation int64 `json:"generation,omitempty"`
+	// claim mounted by new runner pods
+	// +optional
+	Current string `json:"current,omitempty"`
+	// claim being populated by the warm-up job
+	// +optional
+	Pending string `json:"pending,omitempty"`
+	// when the last warm-up job finished, successfully or not
+	// +optional
+	LastWarmUpTime *metav1.Time `json:"lastWarmUpTime,omitempty"`
+}
+
+// GithubAppSecretRef references a secret in the namespace of the CR holding GitHub App credentials
+type GithubAppSecretRef struct {
+	// Name of the secret
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Key holding the app ID
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="GITHUB_APP_INTEGRATION_ID"
+	IntegrationIDKey string `json:"integrationIdKey,omitempty"`
+
+	// Key holding the PEM encoded private key of the app
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="GITHUB_APP_PRIVATE_KEY"
+	PrivateKeyKey string `json:"privateKeyKey,omitempty"`
+
+	// Key holding the installation ID. Optional in the secret, looked up by organization if missing.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="GITHUB_APP_INSTALLATION_ID"
+	InstallationIDKey string `json:"installationIdKey,omitempty"`
+}
+
+// PodNaming selects how runner pods are named
+// +kubebuilder:validation:Enum=Generated;Ordinal
+type PodNaming string
+
+const (
+	// GeneratedPodNaming names pods <name>-pod-<random suffix>.
+	GeneratedPodNaming PodNaming = "Generated"
+	// OrdinalPodNaming names pods <name>-<slot>, reusing the names of replaced pods.
+	OrdinalPodNaming PodNaming = "Ordinal"
+)
+
+// ContainerRuntime is a container runtime sidecar of runner pods
+// +kubebuilder:validation:Enum=None;Dind;RootlessDind;BuildKit
+type ContainerRuntime string
+
+const (
+	// NoContainerRuntime adds no sidecar.
+	NoContainerRuntime ContainerRuntime = "None"
+	// DindRuntime adds a privileged Docker daemon reachable over TLS.
+	DindRuntime ContainerRuntime = "Dind"
+	// RootlessDindRuntime adds a Docker daemon running as an unprivileged user reachable over TLS.
+	RootlessDindRuntime ContainerRuntime = "RootlessDind"
+	// BuildKitRuntime adds a rootless BuildKit daemon listening on localhost.
+	BuildKitRuntime ContainerRuntime = "BuildKit"
+)
+
+// CredentialsSource is where the secrets holding GitHub credentials are stored
+// +kubebuilder:validation:Enum=Secret;Vault;File
+type CredentialsSource string
+
+const (
+	// SecretSource reads Kubernetes Secrets in the namespace of the CR.
+	SecretSource CredentialsSource = "Secret"
+	// VaultSource reads HashiCorp Vault KV secrets below the namespace of the CR.
+	VaultSource CredentialsSource = "Vault"
+	// FileSource reads files mounted into the operator below the namespace of the CR.
+	FileSource CredentialsSource = "File"
+)
+
+const (
+	// LeastRecent first.
+	LeastRecent SortOrder = "LeastRecent"
+	// MostRecent first.
+	MostRecent SortOrder = "MostRecent"
+)
+
+// SortOrder defines order to sort by when sorting on creation timestamp.
+// +kubebuilder:validation:Enum=MostRecent;LeastRecent
+type SortOrder string
+
+// GithubActionRunnerStatus defines the observed state of GithubActionRunner
+type GithubActionRunnerStatus struct {
+	// the current size of the build pool
+	CurrentSize int `json:"currentSize"`
+	// when the registration token in the <name>-regtoken secret expires
+	// +optional
+	RegistrationTokenExpiresAt *metav1.Time `json:"registrationTokenExpiresAt,omitempty"`
+	// generations of the shared cache
+	// +optional
+	SharedCache *SharedCacheStatus `json:"sharedCache,omitempty"`
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Conditions",xDescriptors="urn:alm:descriptor:io.kubernetes.conditions"
+	// Details of the current state of this API Resource.
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// GithubActionRunner is the Schema for the githubactionrunners API
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=githubactionrunners,scope=Namespaced,shortName=gar
+// +kubebuilder:printcolumn:name="currentPoolSize",type=integer,JSONPath=`.status.currentSize`
+// +operator-sdk:csv:customresourcedefinitions:displayName="GitHub Actions Runner"
+type GithubActionRunner struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GithubActionRunnerSpec   `json:"spec,omitempty"`
+	Status GithubActionRunnerStatus `json:"status,omitempty"`
+}
+
+// GetConditions returns details of the current state of this API Resource.
+func (r *GithubActionRunner) GetConditions() []metav1.Condition {
+	return r.Status.Conditions
+}
+
+// SetConditions sets details of the current state of this API Resource.
+func (r *GithubActionRunner) SetConditions(conditions []metav1.Condition) {
+	r.Status.Conditions = conditions
+}
+
+// +kubebuilder:object:root=true
+
+// GithubActionRunnerList contains a list of GithubActionRunner
+type GithubActionRunnerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GithubActionRunner `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GithubActionRunner{}, &GithubActionRunnerList{})
+}
@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// condition types set on GithubActionRunner in addition to the reconcile outcome conditions
+const (
+	rateLimitedCondition    = "RateLimited"
+	quotaThrottledCondition = "QuotaThrottled"
+	pausedCondition         = "Paused"
+	drainingCondition       = "Draining"
+	unschedulableCondition  = "Unschedulable"
+)
+
+// setCondition sets a condition on the CR, it is persisted with the next status update
+func setCondition(instance *garov1alpha1.GithubActionRunner, conditionType string, status bool, reason string, message string) {
+	conditionStatus := metav1.ConditionFalse
+	if status {
+		conditionStatus = metav1.ConditionTrue
+	}
+
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		ObservedGeneration: instance.GetGeneration(),
+		Reason:             reason,
+		Message:            message,
+	})
+}
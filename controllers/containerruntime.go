@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"path"
+
+	"github.com/caitlinelfring/go-env-default"
+	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// images of the container runtime sidecars, to be changed for mirrors
+const dindImageEnvVarName = "GARO_DIND_IMAGE"
+const rootlessDindImageEnvVarName = "GARO_ROOTLESS_DIND_IMAGE"
+const buildKitImageEnvVarName = "GARO_BUILDKIT_IMAGE"
+
+const runtimeContainerName = "garo-runtime"
+const runtimeStorageVolumeName = "garo-runtime-storage"
+const dockerCertsVolumeName = "garo-docker-certs"
+const runnerWorkVolumeName = "garo-runner-work"
+
+// dind generates a CA, server and client certificates below DOCKER_TLS_CERTDIR, the runner only gets the client part
+const dockerCertsPath = "/certs"
+const dockerPort = "2376"
+const buildKitAddress = "tcp://127.0.0.1:1234"
+
+// rootless images run as this user
+const rootlessUser = int64(1000)
+
+// addContainerRuntime adds the container runtime sidecar and points the runner container at it
+func addContainerRuntime(spec *corev1.PodSpec, instance *garov1alpha1.GithubActionRunner, runtime garov1alpha1.ContainerRuntime) error {
+	if runtime == "" || runtime == garov1alpha1.NoContainerRuntime {
+		return nil
+	}
+
+	runner, err := runnerContainer(spec, instance)
+	if err != nil {
+		return err
+	}
+
+	var sidecar corev1.Container
+	switch runtime {
+	case garov1alpha1.DindRuntime:
+		sidecar = dockerSidecar(spec, runner, instance, env.GetDefault(dindImageEnvVarName, "docker:dind"), "/var/lib/docker")
+		sidecar.SecurityContext = &corev1.SecurityContext{Privileged: lo.ToPtr(true)}
+	case garov1alpha1.RootlessDindRuntime:
+		sidecar = dockerSidecar(spec, runner, instance, env.GetDefault(rootlessDindImageEnvVarName, "docker:dind-rootless"), "/home/rootless/.local/share/docker")
+		// the daemon runs as an unprivileged user, but still needs to set up user namespaces
+		sidecar.SecurityContext = &corev1.SecurityContext{
+			Privileged: lo.ToPtr(true),
+			RunAsUser:  lo.ToPtr(rootlessUser),
+			RunAsGroup: lo.ToPtr(rootlessUser),
+		}
+	case garov1alpha1.BuildKitRuntime:
+		sidecar = buildKitSidecar(spec, runner)
+	}
+	spec.Containers = append(spec.Containers, sidecar)
+
+	return nil
+}
+
+// dockerSidecar returns a Docker daemon listening with TLS on localhost, and configures the runner container as client.
+// The work directory of the runner is shared so bind mounts of the workspace resolve in the daemon.
+func dockerSidecar(spec *corev1.PodSpec, runner *corev1.Container, instance *garov1alpha1.GithubActionRunner, image string, storagePath string) corev1.Container {
+	workPath := path.Join(runnerHome(instance), lo.Ternary(instance.Spec.Registration.WorkDir != "", instance.Spec.Registration.WorkDir, "_work"))
+	workVolume := sharedVolume(spec, runner, runnerWorkVolumeName, workPath)
+	addEmptyDir(spec, runtimeStorageVolumeName)
+	addEmptyDir(spec, dockerCertsVolumeName)
+
+	runner.Env = withDefaultEnv(runner.Env, []corev1.EnvVar{
+		{Name: "DOCKER_HOST", Value: "tcp://localhost:" + dockerPort},
+		{Name: "DOCKER_TLS_VERIFY", Value: "1"},
+		{Name: "DOCKER_CERT_PATH", Value: path.Join(dockerCertsPath, "client")},
+	})
+	runner.VolumeMounts = append(runner.VolumeMounts, corev1.VolumeMount{
+		Name:      dockerCertsVolumeName,
+		MountPath: path.Join(dockerCertsPath, "client"),
+		SubPath:   "client",
+		ReadOnly:  true,
+	})
+
+	return corev1.Container{
+		Name:  runtimeContainerName,
+		Image: image,
+		Env:   []corev1.EnvVar{{Name: "DOCKER_TLS_CERTDIR", Value: dockerCertsPath}},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: runtimeStorageVolumeName, MountPath: storagePath},
+			{Name: dockerCertsVolumeName, MountPath: dockerCertsPath},
+			{Name: workVolume, MountPath: workPath},
+		},
+	}
+}
+
+// buildKitSidecar returns a rootless BuildKit daemon, and configures the runner container as client.
+// It only listens on the loopback interface which is not reachable from outside the pod, so it is not set up with TLS.
+func buildKitSidecar(spec *corev1.PodSpec, runner *corev1.Container) corev1.Container {
+	addEmptyDir(spec, runtimeStorageVolumeName)
+	runner.Env = withDefaultEnv(runner.Env, []corev1.EnvVar{{Name: "BUILDKIT_HOST", Value: buildKitAddress}})
+
+	return corev1.Container{
+		Name:  runtimeContainerName,
+		Image: env.GetDefault(buildKitImageEnvVarName, "moby/buildkit:rootless"),
+		Args:  []string{"--addr", buildKitAddress, "--oci-worker-no-process-sandbox"},
+		SecurityContext: &corev1.SecurityContext{
+			RunAsUser:      lo.ToPtr(rootlessUser),
+			RunAsGroup:     lo.ToPtr(rootlessUser),
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined},
+		},
+		VolumeMounts: []corev1.VolumeMount{{Name: runtimeStorageVolumeName, MountPath: "/home/user/.local/share/buildkit"}},
+	}
+}
+
+// sharedVolume returns the volume mounted at mountPath in the container, adding an emptyDir if there is none
+func sharedVolume(spec *corev1.PodSpec, container *corev1.Container, name string, mountPath string) string {
+	mount, found := lo.Find(container.VolumeMounts, func(mount corev1.VolumeMount) bool {
+		return path.Clean(mount.MountPath) == mountPath
+	})
+	if found {
+		return mount.Name
+	}
+
+	addEmptyDir(spec, name)
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{Name: name, MountPath: mountPath})
+
+	return name
+}
+
+func addEmptyDir(spec *corev1.PodSpec, name string) {
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name:         name,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+}
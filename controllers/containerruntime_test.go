@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func volumeNames(spec corev1.PodSpec) []string {
+	return lo.Map(spec.Volumes, func(volume corev1.Volume, _ int) string { return volume.Name })
+}
+
+func TestDindRuntime(t *testing.T) {
+	r := &GithubActionRunnerReconciler{}
+	instance := testRunner()
+	instance.Spec.ContainerRuntime = v1alpha1.DindRuntime
+
+	spec, err := r.runnerPodSpec(context.TODO(), instance)
+	assert.NoError(t, err)
+	assert.Len(t, spec.Containers, 2)
+	assert.ElementsMatch(t, []string{runnerWorkVolumeName, runtimeStorageVolumeName, dockerCertsVolumeName}, volumeNames(spec))
+
+	runner := spec.Containers[0]
+	assert.Contains(t, runner.Env, corev1.EnvVar{Name: "DOCKER_HOST", Value: "tcp://localhost:2376"})
+	assert.Contains(t, runner.Env, corev1.EnvVar{Name: "DOCKER_TLS_VERIFY", Value: "1"})
+	assert.Contains(t, runner.Env, corev1.EnvVar{Name: "DOCKER_CERT_PATH", Value: "/certs/client"})
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: runnerWorkVolumeName, MountPath: "/home/runner/_work"},
+		{Name: dockerCertsVolumeName, MountPath: "/certs/client", SubPath: "client", ReadOnly: true},
+	}, runner.VolumeMounts)
+
+	dind := spec.Containers[1]
+	assert.Equal(t, runtimeContainerName, dind.Name)
+	assert.Equal(t, "docker:dind", dind.Image)
+	assert.True(t, *dind.SecurityContext.Privileged)
+	assert.Equal(t, []corev1.EnvVar{{Name: "DOCKER_TLS_CERTDIR", Value: "/certs"}}, dind.Env)
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: runtimeStorageVolumeName, MountPath: "/var/lib/docker"},
+		{Name: dockerCertsVolumeName, MountPath: "/certs"},
+		{Name: runnerWorkVolumeName, MountPath: "/home/runner/_work"},
+	}, dind.VolumeMounts)
+}
+
+func TestDindRuntimeSharesExistingWorkVolume(t *testing.T) {
+	r := &GithubActionRunnerReconciler{}
+	instance := testRunner()
+	instance.Spec.ContainerRuntime = v1alpha1.RootlessDindRuntime
+	instance.Spec.PodTemplateSpec.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{{Name: "runner-work", MountPath: "/home/runner/_work/"}}
+	instance.Spec.PodTemplateSpec.Spec.Containers[0].Env = append(instance.Spec.PodTemplateSpec.Spec.Containers[0].Env,
+		corev1.EnvVar{Name: "DOCKER_HOST", Value: "tcp://somewhere:2376"})
+
+	spec, err := r.runnerPodSpec(context.TODO(), instance)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{runtimeStorageVolumeName, dockerCertsVolumeName}, volumeNames(spec))
+
+	dockerHost, _ := findEnv(spec.Containers[0].Env, "DOCKER_HOST")
+	assert.Equal(t, "tcp://somewhere:2376", dockerHost.Value)
+
+	dind := spec.Containers[1]
+	assert.Equal(t, "docker:dind-rootless", dind.Image)
+	assert.Equal(t, rootlessUser, *dind.SecurityContext.RunAsUser)
+	assert.Contains(t, dind.VolumeMounts, corev1.VolumeMount{Name: "runner-work", MountPath: "/home/runner/_work"})
+	assert.Contains(t, dind.VolumeMounts, corev1.VolumeMount{Name: runtimeStorageVolumeName, MountPath: "/home/rootless/.local/share/docker"})
+}
+
+func TestBuildKitRuntime(t *testing.T) {
+	r := &GithubActionRunnerReconciler{}
+	instance := testRunner()
+	instance.Spec.ContainerRuntime = v1alpha1.BuildKitRuntime
+
+	spec, err := r.runnerPodSpec(context.TODO(), instance)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{runtimeStorageVolumeName}, volumeNames(spec))
+	assert.Contains(t, spec.Containers[0].Env, corev1.EnvVar{Name: "BUILDKIT_HOST", Value: "tcp://127.0.0.1:1234"})
+
+	buildKit := spec.Containers[1]
+	assert.Equal(t, "moby/buildkit:rootless", buildKit.Image)
+	assert.Equal(t, []string{"--addr", "tcp://127.0.0.1:1234", "--oci-worker-no-process-sandbox"}, buildKit.Args)
+	assert.Nil(t, buildKit.SecurityContext.Privileged)
+	assert.Equal(t, corev1.SeccompProfileTypeUnconfined, buildKit.SecurityContext.SeccompProfile.Type)
+}
+
+func TestCompletedWithContainerRuntime(t *testing.T) {
+	terminated := func(name string, exitCode int32) corev1.ContainerStatus {
+		return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode}}}
+	}
+	running := func(name string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}
+	}
+	pod := func(phase corev1.PodPhase, statuses ...corev1.ContainerStatus) *corev1.Pod {
+		return &corev1.Pod{Status: corev1.PodStatus{Phase: phase, ContainerStatuses: statuses}}
+	}
+
+	assert.True(t, isCompleted(pod(corev1.PodSucceeded, terminated("runner", 0))))
+	// the sidecar keeps the pod running after the runner exited
+	assert.True(t, isCompleted(pod(corev1.PodRunning, terminated("runner", 0), running(runtimeContainerName))))
+	assert.False(t, isCompleted(pod(corev1.PodRunning, running("runner"), running(runtimeContainerName))))
+	assert.False(t, isCompleted(pod(corev1.PodRunning, terminated("runner", 1), running(runtimeContainerName))))
+	assert.False(t, isCompleted(pod(corev1.PodRunning, terminated("runner", 0), running("other"), running(runtimeContainerName))))
+	// without a sidecar it is up to the pod to succeed
+	assert.False(t, isCompleted(pod(corev1.PodRunning, terminated("runner", 0), running("other"))))
+}
@@ -0,0 +1,55 @@
+package credentialprovider
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// CachingProvider keeps the secrets read by another provider for a while, so that sources outside of the cluster are
+// not read on every call. A rotated secret is only seen once its cached copy expires.
+type CachingProvider struct {
+	provider Provider
+	ttl      time.Duration
+
+	mutex   sync.Mutex
+	entries map[types.NamespacedName]cachedSecret
+}
+
+type cachedSecret struct {
+	data    map[string][]byte
+	expires time.Time
+}
+
+// NewCachingProvider returns a provider caching the secrets read by provider for ttl
+func NewCachingProvider(provider Provider, ttl time.Duration) *CachingProvider {
+	return &CachingProvider{
+		provider: provider,
+		ttl:      ttl,
+		entries:  make(map[types.NamespacedName]cachedSecret),
+	}
+}
+
+// SecretData returns the cached data of the secret, or reads it if not cached or expired. Failed reads are not cached.
+func (p *CachingProvider) SecretData(ctx context.Context, namespace string, name string) (map[string][]byte, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	p.mutex.Lock()
+	entry, found := p.entries[key]
+	p.mutex.Unlock()
+	if found && time.Now().Before(entry.expires) {
+		return entry.data, nil
+	}
+
+	data, err := p.provider.SecretData(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.entries[key] = cachedSecret{data: data, expires: time.Now().Add(p.ttl)}
+
+	return data, nil
+}
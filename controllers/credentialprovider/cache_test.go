@@ -0,0 +1,57 @@
+package credentialprovider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingProvider counts the reads of the secrets it returns
+type countingProvider struct {
+	reads int
+	err   error
+}
+
+func (p *countingProvider) SecretData(_ context.Context, _ string, name string) (map[string][]byte, error) {
+	p.reads++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return map[string][]byte{"GH_TOKEN": []byte(name)}, nil
+}
+
+func TestCachingProvider(t *testing.T) {
+	source := &countingProvider{}
+	provider := NewCachingProvider(source, time.Hour)
+	ctx := context.TODO()
+
+	for i := 0; i < 2; i++ {
+		data, err := provider.SecretData(ctx, "someNamespace", "github")
+		assert.NoError(t, err)
+		assert.Equal(t, "github", string(data["GH_TOKEN"]))
+	}
+	assert.Equal(t, 1, source.reads)
+
+	// secrets are cached by namespace and name
+	_, err := provider.SecretData(ctx, "otherNamespace", "github")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, source.reads)
+
+	// failures are not cached
+	source.err = errors.New("unavailable")
+	_, err = provider.SecretData(ctx, "someNamespace", "other")
+	assert.Error(t, err)
+	source.err = nil
+	_, err = provider.SecretData(ctx, "someNamespace", "other")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, source.reads)
+
+	// expired secrets are read again
+	expiring := NewCachingProvider(source, 0)
+	_, _ = expiring.SecretData(ctx, "someNamespace", "github")
+	_, _ = expiring.SecretData(ctx, "someNamespace", "github")
+	assert.Equal(t, 6, source.reads)
+}
@@ -0,0 +1,48 @@
+package credentialprovider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileProvider reads secrets mounted into the operator, e.g. by a CSI secrets store driver.
+// A secret is a directory <Dir>/<namespace>/<name> with one file per key.
+type FileProvider struct {
+	Dir string
+}
+
+// SecretData returns the contents of the files in the directory of the secret
+func (p FileProvider) SecretData(_ context.Context, namespace string, name string) (map[string][]byte, error) {
+	if !validPathElement(namespace) || !validPathElement(name) {
+		return nil, fmt.Errorf("invalid secret name %q", name)
+	}
+
+	dir := filepath.Join(p.Dir, namespace, name)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][]byte)
+	for _, entry := range entries {
+		// skip the ..data style symlinks of projected volumes
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		value, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		data[entry.Name()] = value
+	}
+
+	return data, nil
+}
+
+// validPathElement keeps a CR from referencing files outside of its namespace
+func validPathElement(element string) bool {
+	return element != "" && element != "." && element != ".." && !strings.ContainsAny(element, `/\`)
+}
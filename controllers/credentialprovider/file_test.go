@@ -0,0 +1,28 @@
+package credentialprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	secretDir := filepath.Join(dir, "someNamespace", "github")
+	assert.NoError(t, os.MkdirAll(filepath.Join(secretDir, "..2024_01_01"), 0o700))
+	assert.NoError(t, os.WriteFile(filepath.Join(secretDir, "GH_TOKEN"), []byte("someToken"), 0o600))
+
+	provider := FileProvider{Dir: dir}
+	data, err := provider.SecretData(context.TODO(), "someNamespace", "github")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"GH_TOKEN": []byte("someToken")}, data)
+
+	_, err = provider.SecretData(context.TODO(), "otherNamespace", "github")
+	assert.Error(t, err)
+
+	_, err = provider.SecretData(context.TODO(), "otherNamespace", "..")
+	assert.Error(t, err)
+}
@@ -0,0 +1,30 @@
+// Package credentialprovider reads the secrets holding GitHub credentials of a GithubActionRunner from where they are stored
+package credentialprovider
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Provider returns the keys of a named secret
+type Provider interface {
+	// SecretData returns the keys of the secret with the given name, referenced from a CR in the namespace
+	SecretData(ctx context.Context, namespace string, name string) (map[string][]byte, error)
+}
+
+// SecretProvider reads Kubernetes Secrets in the namespace of the CR
+type SecretProvider struct {
+	Client client.Client
+}
+
+// SecretData returns the data of the Secret
+func (p SecretProvider) SecretData(ctx context.Context, namespace string, name string) (map[string][]byte, error) {
+	var secret corev1.Secret
+	if err := p.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &secret); err != nil {
+		return nil, err
+	}
+
+	return secret.Data, nil
+}
@@ -0,0 +1,183 @@
+package credentialprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caitlinelfring/go-env-default"
+)
+
+// VaultConfig configures access to HashiCorp Vault
+type VaultConfig struct {
+	// Address of Vault, like https://vault.example.com:8200
+	Address string
+	// Token authenticates directly, used if RoleID is empty
+	Token string
+	// RoleID and SecretID authenticate with AppRole
+	RoleID   string
+	SecretID string
+	// AppRoleMount is where the AppRole auth method is enabled
+	AppRoleMount string
+	// KVMount is where the KV version 2 secrets engine is enabled
+	KVMount string
+	// PathPrefix is prepended to <namespace>/<name> when reading secrets
+	PathPrefix string
+}
+
+// VaultConfigFromEnv reads the Vault configuration from the standard Vault and operator environment variables
+func VaultConfigFromEnv() VaultConfig {
+	return VaultConfig{
+		Address:      env.GetDefault("VAULT_ADDR", ""),
+		Token:        env.GetDefault("VAULT_TOKEN", ""),
+		RoleID:       env.GetDefault("VAULT_ROLE_ID", ""),
+		SecretID:     env.GetDefault("VAULT_SECRET_ID", ""),
+		AppRoleMount: env.GetDefault("GARO_VAULT_APPROLE_MOUNT", "approle"),
+		KVMount:      env.GetDefault("GARO_VAULT_KV_MOUNT", "secret"),
+		PathPrefix:   env.GetDefault("GARO_VAULT_PATH_PREFIX", "garo"),
+	}
+}
+
+// VaultProvider reads secrets from a Vault KV version 2 secrets engine.
+// A secret is read from <KVMount>/data/<PathPrefix>/<namespace>/<name>, so CRs can only read secrets of their namespace.
+type VaultProvider struct {
+	config VaultConfig
+	client *http.Client
+
+	mutex        sync.Mutex
+	token        string
+	tokenExpires time.Time
+}
+
+// NewVaultProvider returns a provider for the Vault described by config
+func NewVaultProvider(config VaultConfig) *VaultProvider {
+	return &VaultProvider{
+		config: config,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SecretData returns the data of the latest version of the secret
+func (p *VaultProvider) SecretData(ctx context.Context, namespace string, name string) (map[string][]byte, error) {
+	if !validPathElement(namespace) || !validPathElement(name) {
+		return nil, fmt.Errorf("invalid secret name %q", name)
+	}
+
+	secretPath := path.Join(p.config.KVMount, "data", p.config.PathPrefix, namespace, name)
+	var secret struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	status, err := p.authenticatedCall(ctx, http.MethodGet, secretPath, &secret)
+	if status == http.StatusForbidden && p.config.RoleID != "" {
+		// the AppRole token may have been revoked, log in again
+		p.forgetToken()
+		status, err = p.authenticatedCall(ctx, http.MethodGet, secretPath, &secret)
+	}
+	if status == http.StatusNotFound {
+		return nil, fmt.Errorf("secret %s not found in Vault", secretPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][]byte, len(secret.Data.Data))
+	for key, value := range secret.Data.Data {
+		data[key] = []byte(value)
+	}
+
+	return data, nil
+}
+
+func (p *VaultProvider) authenticatedCall(ctx context.Context, method string, apiPath string, result interface{}) (int, error) {
+	token, err := p.vaultToken(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return p.call(ctx, method, apiPath, token, nil, result)
+}
+
+// vaultToken returns the configured token, or logs in with AppRole when there is no valid token
+func (p *VaultProvider) vaultToken(ctx context.Context) (string, error) {
+	if p.config.RoleID == "" {
+		return p.config.Token, nil
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.token != "" && (p.tokenExpires.IsZero() || time.Now().Before(p.tokenExpires)) {
+		return p.token, nil
+	}
+
+	var login struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	body := map[string]string{"role_id": p.config.RoleID, "secret_id": p.config.SecretID}
+	if _, err := p.call(ctx, http.MethodPost, path.Join("auth", p.config.AppRoleMount, "login"), "", body, &login); err != nil {
+		return "", fmt.Errorf("vault AppRole login failed: %w", err)
+	}
+
+	p.token = login.Auth.ClientToken
+	// a token without lease does not expire, it is only replaced once Vault denies it
+	p.tokenExpires = time.Time{}
+	if login.Auth.LeaseDuration > 0 {
+		// renew ahead of expiry
+		p.tokenExpires = time.Now().Add(time.Duration(login.Auth.LeaseDuration) * time.Second * 9 / 10)
+	}
+
+	return p.token, nil
+}
+
+func (p *VaultProvider) forgetToken() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.token = ""
+}
+
+// call invokes the Vault HTTP API and decodes the response into result, returning the status code
+func (p *VaultProvider) call(ctx context.Context, method string, apiPath string, token string, body interface{}, result interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(p.config.Address, "/")+"/v1/"+apiPath, reader)
+	if err != nil {
+		return 0, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&vaultErr)
+		return resp.StatusCode, fmt.Errorf("vault returned %s for %s: %s", resp.Status, apiPath, strings.Join(vaultErr.Errors, ", "))
+	}
+
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(result)
+}
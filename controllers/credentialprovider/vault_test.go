@@ -0,0 +1,104 @@
+package credentialprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeVault stands in for a Vault dev server with AppRole auth and a KV version 2 engine at secret/
+type fakeVault struct {
+	logins        atomic.Int32
+	revoked       atomic.Bool
+	leaseDuration int
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch req.URL.Path {
+	case "/v1/auth/approle/login":
+		var login map[string]string
+		_ = json.NewDecoder(req.Body).Decode(&login)
+		if login["role_id"] != "someRole" || login["secret_id"] != "someSecretID" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors": ["invalid role or secret ID"]}`)
+			return
+		}
+		v.logins.Add(1)
+		v.revoked.Store(false)
+		fmt.Fprintf(w, `{"auth": {"client_token": "someVaultToken%d", "lease_duration": %d}}`, v.logins.Load(), v.leaseDuration)
+	case "/v1/secret/data/garo/someNamespace/github":
+		if v.revoked.Load() || req.Header.Get("X-Vault-Token") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors": ["permission denied"]}`)
+			return
+		}
+		fmt.Fprint(w, `{"data": {"data": {"GH_TOKEN": "someToken"}, "metadata": {"version": 1}}}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errors": []}`)
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	vault := &fakeVault{leaseDuration: 3600}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	provider := NewVaultProvider(VaultConfig{
+		Address:      server.URL,
+		RoleID:       "someRole",
+		SecretID:     "someSecretID",
+		AppRoleMount: "approle",
+		KVMount:      "secret",
+		PathPrefix:   "garo",
+	})
+	ctx := context.TODO()
+
+	for i := 0; i < 2; i++ {
+		data, err := provider.SecretData(ctx, "someNamespace", "github")
+		assert.NoError(t, err)
+		assert.Equal(t, "someToken", string(data["GH_TOKEN"]))
+	}
+	assert.Equal(t, int32(1), vault.logins.Load())
+
+	// a revoked Vault token leads to a new login
+	vault.revoked.Store(true)
+	_, err := provider.SecretData(ctx, "someNamespace", "github")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), vault.logins.Load())
+
+	_, err = provider.SecretData(ctx, "otherNamespace", "github")
+	assert.ErrorContains(t, err, "not found")
+
+	_, err = provider.SecretData(ctx, "someNamespace", "../../otherNamespace/github")
+	assert.Error(t, err)
+}
+
+func TestVaultProviderNonExpiringToken(t *testing.T) {
+	// Vault reports a lease duration of 0 for tokens without TTL
+	vault := &fakeVault{leaseDuration: 0}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	provider := NewVaultProvider(VaultConfig{
+		Address:      server.URL,
+		RoleID:       "someRole",
+		SecretID:     "someSecretID",
+		AppRoleMount: "approle",
+		KVMount:      "secret",
+		PathPrefix:   "garo",
+	})
+
+	for i := 0; i < 3; i++ {
+		_, err := provider.SecretData(context.TODO(), "someNamespace", "github")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), vault.logins.Load())
+}
@@ -0,0 +1,318 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caitlinelfring/go-env-default"
+	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/evryfs/github-actions-runner-operator/controllers/credentialprovider"
+	"github.com/evryfs/github-actions-runner-operator/controllers/githubapi"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const credentialsValidCondition = "CredentialsValid"
+
+// how long before a token expires to start warning about it
+const tokenExpiryWarningEnvVarName = "GARO_TOKEN_EXPIRY_WARNING"
+
+// expiryWarnings keeps track of when pools were last warned about an expiring token, to warn at most daily
+var expiryWarnings = struct {
+	sync.Mutex
+	last map[types.NamespacedName]time.Time
+}{last: make(map[types.NamespacedName]time.Time)}
+
+// checkCredentials validates the credentials referenced by the CR and probes them at GitHub, reflected in the CredentialsValid condition
+func (r *GithubActionRunnerReconciler) checkCredentials(ctx context.Context, instance *garov1alpha1.GithubActionRunner) error {
+	credentials, err := r.credentialsForRef(ctx, instance)
+	if err != nil {
+		setCondition(instance, credentialsValidCondition, false, "InvalidReference", err.Error())
+		return err
+	}
+	spec, err := r.runnerSpec(ctx, instance)
+	if err != nil {
+		return err
+	}
+
+	status, err := r.GithubAPI.CheckCredentials(ctx, instance.Spec.Organization, credentials)
+	if err != nil {
+		if _, rateLimited := rateLimitedError(err); !rateLimited {
+			setCondition(instance, credentialsValidCondition, false, "Rejected", err.Error())
+		}
+		return err
+	}
+
+	message := "Authenticated at GitHub"
+	if len(status.Scopes) > 0 {
+		message += fmt.Sprintf(", scopes %s", strings.Join(status.Scopes, ", "))
+	}
+	if status.ExpiresAt != nil {
+		message += fmt.Sprintf(", expires %s", status.ExpiresAt.Format(time.RFC3339))
+		r.warnIfExpiring(instance, credentialsSecretName(spec), *status.ExpiresAt)
+	}
+	setCondition(instance, credentialsValidCondition, true, "Authenticated", message)
+
+	return nil
+}
+
+// warnIfExpiring emits an event if the token expires soon
+func (r *GithubActionRunnerReconciler) warnIfExpiring(instance *garov1alpha1.GithubActionRunner, secretName string, expiresAt time.Time) {
+	if time.Until(expiresAt) > env.GetDurationDefault(tokenExpiryWarningEnvVarName, 7*24*time.Hour) {
+		return
+	}
+
+	pool := client.ObjectKeyFromObject(instance)
+	expiryWarnings.Lock()
+	defer expiryWarnings.Unlock()
+	if time.Since(expiryWarnings.last[pool]) < 24*time.Hour {
+		return
+	}
+	expiryWarnings.last[pool] = time.Now()
+	r.GetRecorder().Eventf(instance, corev1.EventTypeWarning, "TokenExpiring", "GitHub token in secret %s expires %s", secretName, expiresAt.Format(time.RFC3339))
+}
+
+// forgetExpiryWarning drops the warning state of a pool that no longer exists
+func forgetExpiryWarning(pool types.NamespacedName) {
+	expiryWarnings.Lock()
+	defer expiryWarnings.Unlock()
+	delete(expiryWarnings.last, pool)
+}
+
+// credentialsSecretIndex indexes GithubActionRunners by the name of the secret holding their credentials
+const credentialsSecretIndex = "spec.credentialsSecret"
+
+func indexCredentialsSecret(obj client.Object) []string {
+	runner := obj.(*garov1alpha1.GithubActionRunner)
+	if name := credentialsSecretName(&runner.Spec); name != "" && credentialsSource(&runner.Spec) == garov1alpha1.SecretSource {
+		return []string{name}
+	}
+	return nil
+}
+
+// referencingRunner is a GithubActionRunner reading its credentials from a secret, with its spec merged with its class
+type referencingRunner struct {
+	key  types.NamespacedName
+	spec *garov1alpha1.GithubActionRunnerSpec
+}
+
+// runnersReferencingSecret returns the GithubActionRunners reading their credentials from the secret, either
+// referenced in their own spec or inherited from their class
+func (r *GithubActionRunnerReconciler) runnersReferencingSecret(ctx context.Context, secret client.Object) ([]referencingRunner, error) {
+	runnerList := &garov1alpha1.GithubActionRunnerList{}
+	if err := r.GetClient().List(ctx, runnerList, client.InNamespace(secret.GetNamespace()), client.MatchingFields{credentialsSecretIndex: secret.GetName()}); err != nil {
+		return nil, err
+	}
+	runners := lo.Map(runnerList.Items, func(runner garov1alpha1.GithubActionRunner, _ int) referencingRunner {
+		return referencingRunner{key: client.ObjectKeyFromObject(&runner), spec: runner.Spec.DeepCopy()}
+	})
+
+	// the index only knows the refs of the spec, refs of a class are found through the classes referencing the secret
+	classList := &garov1alpha1.ClusterGithubActionRunnerClassList{}
+	if err := r.GetClient().List(ctx, classList, client.MatchingFields{classCredentialsSecretIndex: secret.GetName()}); err != nil {
+		return nil, err
+	}
+	for i := range classList.Items {
+		class := &classList.Items[i]
+		classedList := &garov1alpha1.GithubActionRunnerList{}
+		if err := r.GetClient().List(ctx, classedList, client.InNamespace(secret.GetNamespace()), client.MatchingFields{runnerClassIndex: class.Name}); err != nil {
+			return nil, err
+		}
+		for j := range classedList.Items {
+			runner := &classedList.Items[j]
+			if credentialsSecretName(&runner.Spec) != "" {
+				continue
+			}
+			spec := runner.Spec.DeepCopy()
+			if err := spec.ApplyClass(&class.Spec); err != nil {
+				r.Log.V(1).Info("Skipping runner whose class cannot be merged", "runner", runner.Name, "error", err.Error())
+				continue
+			}
+			runners = append(runners, referencingRunner{key: client.ObjectKeyFromObject(runner), spec: spec})
+		}
+	}
+
+	return runners, nil
+}
+
+// ownedByRunner tells whether the secret is managed by the operator for a GithubActionRunner, like its registration token
+func ownedByRunner(secret client.Object) bool {
+	owner := metav1.GetControllerOf(secret)
+	return owner != nil && owner.Kind == "GithubActionRunner" && strings.HasPrefix(owner.APIVersion, garov1alpha1.GroupVersion.Group+"/")
+}
+
+// credentialsSecretHandler enqueues the GithubActionRunners referencing a secret when it changes.
+// Anything cached for the credentials in the previous version of the secret is invalidated.
+func (r *GithubActionRunnerReconciler) credentialsSecretHandler() handler.EventHandler {
+	enqueue := func(ctx context.Context, secret client.Object, oldSecret *corev1.Secret, queue workqueue.RateLimitingInterface) {
+		// secrets of the operator hold no credentials and are followed as owned objects
+		if ownedByRunner(secret) {
+			return
+		}
+
+		runners, err := r.runnersReferencingSecret(ctx, secret)
+		if err != nil {
+			r.Log.Error(err, "Failed to list runners referencing secret", "secret", secret.GetName())
+			return
+		}
+
+		for _, runner := range runners {
+			if oldSecret != nil {
+				if credentials, err := credentialsFromData(runner.spec, oldSecret.Name, oldSecret.Data); err == nil {
+					r.GithubAPI.InvalidateCredentials(runner.spec.Organization, credentials)
+				}
+			}
+			queue.Add(reconcile.Request{NamespacedName: runner.key})
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, queue workqueue.RateLimitingInterface) {
+			enqueue(ctx, e.Object, nil, queue)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, queue workqueue.RateLimitingInterface) {
+			oldSecret, newSecret := e.ObjectOld.(*corev1.Secret), e.ObjectNew.(*corev1.Secret)
+			if equality.Semantic.DeepEqual(oldSecret.Data, newSecret.Data) {
+				return
+			}
+			enqueue(ctx, newSecret, oldSecret, queue)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, queue workqueue.RateLimitingInterface) {
+			enqueue(ctx, e.Object, e.Object.(*corev1.Secret), queue)
+		},
+	}
+}
+
+// resolvedCredentialsKey is the context key of the credentials resolved during a reconcile
+type resolvedCredentialsKey struct{}
+
+// withResolvedCredentials returns a context in which the credentials of a CR are only resolved once, so a reconcile
+// reads the referenced secret once however many calls it makes to GitHub. Rotated credentials are picked up by the
+// next reconcile.
+func withResolvedCredentials(ctx context.Context) context.Context {
+	return context.WithValue(ctx, resolvedCredentialsKey{}, map[types.NamespacedName]githubapi.Credentials{})
+}
+
+// credentialsForRef returns the credentials referenced from the GithubActionRunner Spec.TokenRef or Spec.AppSecretRef
+func (r *GithubActionRunnerReconciler) credentialsForRef(ctx context.Context, cr *garov1alpha1.GithubActionRunner) (githubapi.Credentials, error) {
+	resolved, _ := ctx.Value(resolvedCredentialsKey{}).(map[types.NamespacedName]githubapi.Credentials)
+	if credentials, found := resolved[client.ObjectKeyFromObject(cr)]; found {
+		return credentials, nil
+	}
+
+	spec, err := r.runnerSpec(ctx, cr)
+	if err != nil {
+		return githubapi.Credentials{}, err
+	}
+	name := credentialsSecretName(spec)
+	var data map[string][]byte
+	if name != "" {
+		provider, err := r.credentialsProvider(spec)
+		if err != nil {
+			return githubapi.Credentials{}, err
+		}
+		if data, err = provider.SecretData(ctx, cr.Namespace, name); err != nil {
+			return githubapi.Credentials{}, err
+		}
+	}
+
+	credentials, err := credentialsFromData(spec, name, data)
+	if err == nil && resolved != nil {
+		resolved[client.ObjectKeyFromObject(cr)] = credentials
+	}
+	return credentials, err
+}
+
+// credentialsProvider returns the provider for the credentials source of the spec
+func (r *GithubActionRunnerReconciler) credentialsProvider(spec *garov1alpha1.GithubActionRunnerSpec) (credentialprovider.Provider, error) {
+	source := credentialsSource(spec)
+	if source == garov1alpha1.SecretSource {
+		return credentialprovider.SecretProvider{Client: r.GetClient()}, nil
+	}
+
+	provider, found := r.CredentialsProviders[source]
+	if !found {
+		return nil, fmt.Errorf("credentials source %s is not configured on the operator", source)
+	}
+	return provider, nil
+}
+
+func credentialsSource(spec *garov1alpha1.GithubActionRunnerSpec) garov1alpha1.CredentialsSource {
+	return lo.Ternary(spec.CredentialsSource != "", spec.CredentialsSource, garov1alpha1.SecretSource)
+}
+
+// credentialsSecretName returns the name of the secret holding the credentials of the spec, empty for the operator wide app
+func credentialsSecretName(spec *garov1alpha1.GithubActionRunnerSpec) string {
+	if spec.TokenRef.Name != "" {
+		return spec.TokenRef.Name
+	}
+	if spec.AppSecretRef != nil {
+		return spec.AppSecretRef.Name
+	}
+
+	return ""
+}
+
+// credentialsFromData reads the credentials of the spec from the data of its referenced secret
+func credentialsFromData(spec *garov1alpha1.GithubActionRunnerSpec, name string, data map[string][]byte) (githubapi.Credentials, error) {
+	if spec.TokenRef.Name != "" {
+		token := strings.TrimSpace(string(data[spec.TokenRef.Key]))
+		if token == "" {
+			return githubapi.Credentials{}, fmt.Errorf("no token in key %s of secret %s", spec.TokenRef.Key, spec.TokenRef.Name)
+		}
+		return githubapi.Credentials{Token: token}, nil
+	}
+
+	credentials := githubapi.Credentials{}
+	if ref := spec.AppSecretRef; ref != nil {
+		var err error
+		if credentials, err = appCredentials(ref, name, data); err != nil {
+			return credentials, err
+		}
+	}
+
+	// an installation pinned in the CR wins over the one in the secret
+	if spec.InstallationID != 0 {
+		credentials.InstallationID = spec.InstallationID
+	}
+
+	return credentials, nil
+}
+
+// appCredentials reads the GitHub App credentials from the referenced secret
+func appCredentials(ref *garov1alpha1.GithubAppSecretRef, name string, data map[string][]byte) (githubapi.Credentials, error) {
+	integrationIDKey := lo.Ternary(ref.IntegrationIDKey != "", ref.IntegrationIDKey, "GITHUB_APP_INTEGRATION_ID")
+	privateKeyKey := lo.Ternary(ref.PrivateKeyKey != "", ref.PrivateKeyKey, "GITHUB_APP_PRIVATE_KEY")
+	installationIDKey := lo.Ternary(ref.InstallationIDKey != "", ref.InstallationIDKey, "GITHUB_APP_INSTALLATION_ID")
+
+	integrationID, err := strconv.ParseInt(strings.TrimSpace(string(data[integrationIDKey])), 10, 64)
+	if err != nil {
+		return githubapi.Credentials{}, fmt.Errorf("invalid app ID in key %s of secret %s: %w", integrationIDKey, name, err)
+	}
+
+	privateKey := data[privateKeyKey]
+	if len(privateKey) == 0 {
+		return githubapi.Credentials{}, fmt.Errorf("no private key in key %s of secret %s", privateKeyKey, name)
+	}
+
+	credentials := githubapi.Credentials{App: &githubapi.AppCredentials{IntegrationID: integrationID, PrivateKey: privateKey}}
+	if value, found := data[installationIDKey]; found {
+		if credentials.InstallationID, err = strconv.ParseInt(strings.TrimSpace(string(value)), 10, 64); err != nil {
+			return githubapi.Credentials{}, fmt.Errorf("invalid installation ID in key %s of secret %s: %w", installationIDKey, name, err)
+		}
+	}
+
+	return credentials, nil
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// drain removes all idle runners of the instance regardless of minRunners and minTtl, busy runners are removed by
+// later reconciles once they finished their job. Pods whose runner is not listed yet are left until it is, as it
+// could already have taken a job.
+func (r *GithubActionRunnerReconciler) drain(ctx context.Context, instance *garov1alpha1.GithubActionRunner, podRunnerPairs podRunnerPairList) (reconcile.Result, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+	setCondition(instance, quotaThrottledCondition, false, "Draining", "")
+	recordPoolState(client.ObjectKeyFromObject(instance), podRunnerPairs, 0)
+
+	removed := 0
+	for _, pair := range podRunnerPairs.getIdles(instance.Spec.DeletionOrder, 0) {
+		if pair.runner.GetID() == 0 {
+			continue
+		}
+		ok, err := r.removeIdleRunner(ctx, instance, pair, scaleReasonDrain)
+		if err != nil {
+			return r.manageOutcome(ctx, instance, err)
+		}
+		if ok {
+			removed++
+		}
+	}
+
+	remaining := podRunnerPairs.numPods() - removed
+	if remaining == 0 {
+		setCondition(instance, drainingCondition, true, "Drained", "All runners are removed")
+	} else {
+		logger.Info("Draining", "removed", removed, "remaining", remaining)
+		setCondition(instance, drainingCondition, true, "Draining", fmt.Sprintf("Waiting for %d runners to finish", remaining))
+	}
+	instance.Status.CurrentSize = remaining
+
+	return r.manageOutcome(ctx, instance, nil)
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/google/go-github/v59/github"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestPausedAndDrain(t *testing.T) {
+	ctx := context.TODO()
+	runner := testRunner()
+	runner.UID = "someUID"
+	runner.TypeMeta = metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "GithubActionRunner"}
+	runner.Spec.MinRunners = 3
+
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerList{}, &v1alpha1.GithubActionRunnerInstance{}, &v1alpha1.GithubActionRunnerQuota{}, &v1alpha1.GithubActionRunnerQuotaList{})
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(runner).WithStatusSubresource(&v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerInstance{}).Build()
+	api := new(mockAPI)
+	r := &GithubActionRunnerReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, record.NewFakeRecorder(20), nil), Log: zap.New(), GithubAPI: api}
+
+	// a paused pool makes no calls to GitHub
+	runner.Spec.Paused = true
+	_, err := r.handleScaling(ctx, runner)
+	assert.NoError(t, err)
+	assert.True(t, meta.IsStatusConditionTrue(runner.Status.Conditions, pausedCondition))
+	api.AssertNotCalled(t, "GetRunners", mock.Anything, mock.Anything, mock.Anything)
+
+	// a busy runner, an idle runner and a pod whose runner is not listed yet
+	assert.Equal(t, 3, lo.Must(r.scaleUp(ctx, 3, runner)))
+	pods := lo.Must(r.listRelatedPods(ctx, runner))
+	runners := []*github.Runner{
+		{ID: github.Int64(1), Name: github.String(pods.Items[0].Name), Status: github.String("online"), Busy: github.Bool(true)},
+		{ID: github.Int64(2), Name: github.String(pods.Items[1].Name), Status: github.String("online"), Busy: github.Bool(false)},
+	}
+	api.On("GetRunners", "someOrg", "someRepo", mock.Anything).Return(runners, nil)
+
+	runner.Spec.Paused = false
+	runner.Spec.Drain = true
+	_, err = r.drain(ctx, runner, lo.Must(r.getPodRunnerPairs(ctx, runner)))
+	assert.NoError(t, err)
+	// only the idle runner is removed
+	remaining := lo.Must(r.listRelatedPods(ctx, runner)).Items
+	assert.Len(t, remaining, 2)
+	assert.NotContains(t, lo.Map(remaining, func(pod corev1.Pod, _ int) string { return pod.Name }), pods.Items[1].Name)
+	draining := meta.FindStatusCondition(runner.Status.Conditions, drainingCondition)
+	assert.Equal(t, metav1.ConditionTrue, draining.Status)
+	assert.Equal(t, "Draining", draining.Reason)
+	assert.Equal(t, "Waiting for 2 runners to finish", draining.Message)
+
+	// the runner of a pod deleted while the pool is paused is still unregistered
+	runner.Spec.Drain = false
+	runner.Spec.Paused = true
+	assert.NoError(t, cl.Delete(ctx, &remaining[0]))
+	_, err = r.handleScaling(ctx, runner)
+	assert.NoError(t, err)
+	left := lo.Must(r.listRelatedPods(ctx, runner)).Items
+	assert.Len(t, left, 1)
+	assert.Equal(t, remaining[1].Name, left[0].Name)
+	assert.True(t, util.HasFinalizer(&left[0], finalizer))
+}
@@ -26,15 +26,18 @@ import (
 	"time"
 
 	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/evryfs/github-actions-runner-operator/controllers/credentialprovider"
 	"github.com/evryfs/github-actions-runner-operator/controllers/githubapi"
 	"github.com/go-logr/logr"
 	"github.com/google/go-github/v59/github"
 	"github.com/redhat-cop/operator-utils/pkg/util"
 	"github.com/samber/lo"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -55,6 +58,8 @@ type GithubActionRunnerReconciler struct {
 	util.ReconcilerBase
 	Log       logr.Logger
 	GithubAPI githubapi.IRunnerAPI
+	// CredentialsProviders read credentials from sources other than Kubernetes Secrets
+	CredentialsProviders map[garov1alpha1.CredentialsSource]credentialprovider.Provider
 }
 
 // IsValid validates the CR and returns false if it is not valid along with the validation errors, else true and nil
@@ -69,15 +74,21 @@ func (r *GithubActionRunnerReconciler) IsValid(obj metav1.Object) (bool, error)
 // +kubebuilder:rbac:groups=garo.tietoevry.com,resources=githubactionrunners,verbs="*"
 // +kubebuilder:rbac:groups=garo.tietoevry.com,resources=githubactionrunners/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=garo.tietoevry.com,resources=githubactionrunners/finalizers,verbs=update
+// +kubebuilder:rbac:groups=garo.tietoevry.com,resources=clustergithubactionrunnerclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=garo.tietoevry.com,resources=githubactionrunnerquotas,verbs=get;list;watch
+// +kubebuilder:rbac:groups=garo.tietoevry.com,resources=githubactionrunnerinstances,verbs="*"
+// +kubebuilder:rbac:groups=garo.tietoevry.com,resources=githubactionrunnerinstances/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=pods,verbs="*"
 // +kubebuilder:rbac:groups="",resources=secrets,verbs="*"
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs="*"
 
 // Reconcile is the main loop implementing the controller action
 func (r *GithubActionRunnerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	reqLogger := r.Log.WithValues("githubactionrunner", req.NamespacedName)
-	ctx = logr.NewContext(ctx, reqLogger)
+	ctx = withMergedSpecs(withResolvedCredentials(logr.NewContext(ctx, reqLogger)))
 	reqLogger.Info("Reconciling GithubActionRunner")
 
 	// Fetch the GithubActionRunner instance
@@ -87,13 +98,28 @@ func (r *GithubActionRunnerReconciler) Reconcile(ctx context.Context, req ctrl.R
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			deletePoolMetrics(req.NamespacedName)
+			forgetExpiryWarning(req.NamespacedName)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return r.manageOutcome(ctx, instance, err)
 	}
 
-	if ok, err := r.IsValid(instance); !ok {
+	if !instance.DeletionTimestamp.IsZero() {
+		return r.teardown(ctx, instance)
+	}
+
+	if err := r.ensurePoolFinalizer(ctx, instance); err != nil {
+		return r.manageOutcome(ctx, instance, err)
+	}
+
+	// the pool is valid once merged with its class
+	spec, err := r.runnerSpec(ctx, instance)
+	if err != nil {
+		return r.manageOutcome(ctx, instance, err)
+	}
+	if ok, err := spec.IsValid(); !ok {
 		return r.manageOutcome(ctx, instance, err)
 	}
 
@@ -103,73 +129,148 @@ func (r *GithubActionRunnerReconciler) Reconcile(ctx context.Context, req ctrl.R
 // handleScaling is the main logic of the controller
 func (r *GithubActionRunnerReconciler) handleScaling(ctx context.Context, instance *garov1alpha1.GithubActionRunner) (reconcile.Result, error) {
 	logger := logr.FromContextOrDiscard(ctx)
+	if instance.Spec.Paused {
+		logger.Info("Pool is paused, skipping scaling")
+		setCondition(instance, pausedCondition, true, "Paused", "Scaling is suspended, only runners of deleted pods are unregistered")
+		setCondition(instance, quotaThrottledCondition, false, "Paused", "")
+		r.recordLastObservedPoolState(ctx, instance)
+		return r.manageOutcome(ctx, instance, r.unregisterDeletedPods(ctx, instance))
+	}
+	setCondition(instance, pausedCondition, false, "Running", "")
+
+	if err := r.checkCredentials(ctx, instance); err != nil {
+		return r.manageOutcome(ctx, instance, err)
+	}
+
+	retryAfter, rateLimited, err := r.rateLimitBackoff(ctx, instance)
+	if err != nil {
+		return r.manageOutcome(ctx, instance, err)
+	}
+	if rateLimited {
+		return r.manageRateLimited(ctx, instance, retryAfter, lowQuotaMessage(retryAfter))
+	}
+	setCondition(instance, rateLimitedCondition, false, "QuotaAvailable", "")
+
 	podRunnerPairs, err := r.getPodRunnerPairs(ctx, instance)
 	if err != nil {
 		return r.manageOutcome(ctx, instance, err)
 	}
 
+	if err := r.updateRunnerInstances(ctx, instance, podRunnerPairs); err != nil {
+		return r.manageOutcome(ctx, instance, err)
+	}
+
 	// keep the registration token fresh
 	if err := r.createOrUpdateRegistrationTokenSecret(ctx, instance); err != nil {
 		return r.manageOutcome(ctx, instance, err)
 	}
 
+	if err := r.reconcileSharedCache(ctx, instance, podRunnerPairs); err != nil {
+		return r.manageOutcome(ctx, instance, err)
+	}
+
 	// safety guard - always look for finalizers in order to unregister runners for pods about to delete
 	// pods could have been deleted by user directly and not through operator
 	if err = r.handleFinalization(ctx, instance, podRunnerPairs); err != nil {
 		return r.manageOutcome(ctx, instance, err)
 	}
 
+	// a draining pool only removes runners, also while pods and runners are not in sync
+	if instance.Spec.Drain {
+		return r.drain(ctx, instance, podRunnerPairs)
+	}
+	setCondition(instance, drainingCondition, false, "NotDraining", "")
+
+	// unschedulable pods never get a runner, make room for them before waiting for pods and runners to be in sync
+	if err := r.reclaimForUnschedulablePods(ctx, instance, podRunnerPairs); err != nil {
+		return r.manageOutcome(ctx, instance, err)
+	}
+
+	pool := client.ObjectKeyFromObject(instance)
 	if !podRunnerPairs.inSync() {
+		recordPoolState(pool, podRunnerPairs, podRunnerPairs.numPods())
 		logger.Info("Pods and runner API not in sync, returning early")
 		return r.manageOutcome(ctx, instance, nil)
 	}
 
+	if !shouldScaleUp(podRunnerPairs, instance) {
+		setCondition(instance, quotaThrottledCondition, false, "WithinQuota", "")
+	}
+
 	if shouldScaleUp(podRunnerPairs, instance) {
 		instance.Status.CurrentSize = podRunnerPairs.numPods()
 
 		scale := lo.Max([]int{instance.Spec.MinRunners - podRunnerPairs.numRunners(), 1})
+		recordPoolState(pool, podRunnerPairs, podRunnerPairs.numRunners()+scale)
 		logger.Info("Scaling up", "numInstances", scale)
 
-		if err := r.scaleUp(ctx, scale, instance); err != nil {
+		created, err := r.scaleUp(ctx, scale, instance)
+		if err != nil {
 			return r.manageOutcome(ctx, instance, err)
 		}
+		if created < scale {
+			if _, err := r.reclaimIdleRunners(ctx, instance, scale-created); err != nil {
+				return r.manageOutcome(ctx, instance, err)
+			}
+		}
+		scaleUps.WithLabelValues(pool.Namespace, pool.Name, scaleUpReason(podRunnerPairs, instance)).Add(float64(created))
 
-		instance.Status.CurrentSize += scale
+		instance.Status.CurrentSize += created
 		err = r.GetClient().Status().Update(ctx, instance)
 
 		return r.manageOutcome(ctx, instance, err)
 	} else if shouldScaleDown(podRunnerPairs, instance) {
+		recordPoolState(pool, podRunnerPairs, podRunnerPairs.numRunners()-1)
 		logger.Info("Scaling down", "runners at github", podRunnerPairs.numRunners(), "maxrunners in CR", instance.Spec.MaxRunners)
-		err := r.scaleDown(ctx, podRunnerPairs, instance)
+		_, err := r.scaleDown(ctx, podRunnerPairs, instance)
 		return r.manageOutcome(ctx, instance, err)
 	}
 
+	recordPoolState(pool, podRunnerPairs, podRunnerPairs.numRunners())
+	// only when not scaling, pods created by this reconcile would not be seen yet
+	if usesSlots(instance) {
+		err = r.cleanupVolumeClaims(ctx, instance, podRunnerPairs)
+	}
 	return r.manageOutcome(ctx, instance, err)
 }
 
-// scaleDown will scale down an idle runner based on policy in CR
-func (r *GithubActionRunnerReconciler) scaleDown(ctx context.Context, podRunnerPairs podRunnerPairList, instance *garov1alpha1.GithubActionRunner) error {
+// scaleDown will scale down an idle runner based on policy in CR, and tells if it found one to remove
+func (r *GithubActionRunnerReconciler) scaleDown(ctx context.Context, podRunnerPairs podRunnerPairList, instance *garov1alpha1.GithubActionRunner) (bool, error) {
 	idles := podRunnerPairs.getIdles(instance.Spec.DeletionOrder, instance.Spec.MinTTL.Duration)
 	for _, pair := range idles {
-		err := r.unregisterRunner(ctx, instance, pair)
-		if err != nil { // should be improved, here we just assume it's because it's running a job and cannot be removed, skip to next candidate
+		removed, err := r.removeIdleRunner(ctx, instance, pair, scaleDownReason(podRunnerPairs, instance))
+		if err != nil {
+			return false, err
+		}
+		if !removed {
 			continue
 		}
+		err = r.GetClient().Status().Update(ctx, instance)
 
-		//then actually delete the pod
-		err = r.DeleteResourceIfExists(ctx, &pair.pod)
-		if err != nil {
-			return err
-		}
+		return true, err
+	}
 
-		r.GetRecorder().Event(instance, corev1.EventTypeNormal, "Scaling", pair.getNamespacedName())
-		instance.Status.CurrentSize--
-		err = r.GetClient().Status().Update(ctx, instance)
+	return false, nil
+}
 
-		return err
+// removeIdleRunner unregisters the runner of the pair and deletes its pod, and tells if the runner could be unregistered
+func (r *GithubActionRunnerReconciler) removeIdleRunner(ctx context.Context, instance *garov1alpha1.GithubActionRunner, pair podRunnerPair, reason string) (bool, error) {
+	err := r.unregisterRunner(ctx, instance, pair)
+	if err != nil { // should be improved, here we just assume it's because it's running a job and cannot be removed, skip to next candidate
+		return false, nil
 	}
 
-	return nil
+	//then actually delete the pod
+	err = r.DeleteResourceIfExists(ctx, &pair.pod)
+	if err != nil {
+		return false, err
+	}
+
+	r.GetRecorder().Event(instance, corev1.EventTypeNormal, "Scaling", pair.getNamespacedName())
+	scaleDowns.WithLabelValues(instance.Namespace, instance.Name, reason).Inc()
+	instance.Status.CurrentSize--
+
+	return true, nil
 }
 
 func shouldScaleUp(podRunnerPairs podRunnerPairList, instance *garov1alpha1.GithubActionRunner) bool {
@@ -180,23 +281,70 @@ func shouldScaleDown(podRunnerPairs podRunnerPairList, instance *garov1alpha1.Gi
 	return podRunnerPairs.numRunners() > instance.Spec.MaxRunners || (podRunnerPairs.numIdle() > 1 && (podRunnerPairs.numRunners() > instance.Spec.MinRunners))
 }
 
+func scaleUpReason(podRunnerPairs podRunnerPairList, instance *garov1alpha1.GithubActionRunner) string {
+	if podRunnerPairs.numRunners() < instance.Spec.MinRunners {
+		return scaleReasonBelowMinimum
+	}
+	return scaleReasonAllBusy
+}
+
+func scaleDownReason(podRunnerPairs podRunnerPairList, instance *garov1alpha1.GithubActionRunner) string {
+	if podRunnerPairs.numRunners() > instance.Spec.MaxRunners {
+		return scaleReasonAboveMaximum
+	}
+	return scaleReasonIdle
+}
+
 func (r *GithubActionRunnerReconciler) manageOutcome(ctx context.Context, instance *garov1alpha1.GithubActionRunner, issue error) (reconcile.Result, error) {
-	return r.ManageOutcomeWithRequeue(ctx, instance, issue, instance.Spec.ReconciliationPeriod.Duration)
+	if rateLimited, ok := rateLimitedError(issue); ok {
+		return r.manageRateLimited(ctx, instance, rateLimited.RetryAfter, rateLimited.Error())
+	}
+	return r.ManageOutcomeWithRequeue(ctx, instance, issue, requeueAfter(instance))
+}
+
+// requeueAfter returns the reconciliation period, or less if the registration token or shared cache needs refreshing before that
+func requeueAfter(instance *garov1alpha1.GithubActionRunner) time.Duration {
+	period := instance.Spec.ReconciliationPeriod.Duration
+	if expiresAt := instance.Status.RegistrationTokenExpiresAt; expiresAt != nil {
+		untilRefresh := time.Until(expiresAt.Add(-instance.Spec.RegistrationTokenRefreshMargin.Duration))
+		period = lo.Min([]time.Duration{period, lo.Max([]time.Duration{untilRefresh, time.Second})})
+	}
+	if untilRefresh, ok := untilSharedCacheRefresh(instance); ok {
+		period = lo.Min([]time.Duration{period, lo.Max([]time.Duration{untilRefresh, time.Second})})
+	}
+
+	return period
 }
 
 // SetupWithManager configures the controller by using the passed mgr
 func (r *GithubActionRunnerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &garov1alpha1.GithubActionRunner{}, credentialsSecretIndex, indexCredentialsSecret); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &garov1alpha1.GithubActionRunner{}, runnerClassIndex, indexRunnerClass); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &garov1alpha1.ClusterGithubActionRunnerClass{}, classCredentialsSecretIndex, indexClassCredentialsSecret); err != nil {
+		return err
+	}
+
+	// ignore updates to status: https://stuartleeks.com/posts/kubebuilder-event-filters-part-2-update/
+	generationChanged := builder.WithPredicates(predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return e.ObjectNew.GetGeneration() != e.ObjectOld.GetGeneration()
+		},
+	})
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&garov1alpha1.GithubActionRunner{}).
-		Owns(&corev1.Pod{}).
-		Owns(&corev1.Secret{}).
+		For(&garov1alpha1.GithubActionRunner{}, generationChanged).
+		Owns(&corev1.Pod{}, generationChanged).
+		Owns(&corev1.Secret{}, generationChanged).
+		// warm-up jobs are followed by their status
+		Owns(&batchv1.Job{}).
+		// secrets have no generation, rotated credentials are picked up by their data changing
+		Watches(&corev1.Secret{}, r.credentialsSecretHandler()).
+		Watches(&garov1alpha1.ClusterGithubActionRunnerClass{}, r.runnerClassHandler(), generationChanged).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
-		WithEventFilter(predicate.Funcs{
-			// ignore updates to status: https://stuartleeks.com/posts/kubebuilder-event-filters-part-2-update/
-			UpdateFunc: func(e event.UpdateEvent) bool {
-				return e.ObjectNew.GetGeneration() != e.ObjectOld.GetGeneration()
-			},
-		}).
 		Complete(r)
 }
 
@@ -224,32 +372,33 @@ func (r *GithubActionRunnerReconciler) createOrUpdateRegistrationTokenSecret(ctx
 		return err
 	}
 
-	// else found and check validity
+	// else found and check validity, a missing or corrupt expiry is treated as expired
 	epoch, err := strconv.ParseInt(secret.Annotations[registrationTokenExpiresAtAnnotation], 10, 64)
-	if err != nil {
-		return err
+	if err != nil || len(secret.Data[registrationTokenKey]) == 0 {
+		logger.Info("Registration secret invalid, regenerating", "annotation", secret.Annotations[registrationTokenExpiresAtAnnotation])
+		return r.updateRegistrationToken(ctx, instance, secret)
 	}
 
-	// allow for 5 minute clock-skew
-	expired := time.Now().Add(5 * time.Minute).After(time.Unix(epoch, 0))
-	if expired {
+	expiresAt := time.Unix(epoch, 0)
+	if time.Now().Add(instance.Spec.RegistrationTokenRefreshMargin.Duration).After(expiresAt) {
 		logger.Info("Registration token expired, updating")
 		return r.updateRegistrationToken(ctx, instance, secret)
 	}
+	instance.Status.RegistrationTokenExpiresAt = &metav1.Time{Time: expiresAt}
 
-	return err
+	return nil
 }
 
 func (r *GithubActionRunnerReconciler) updateRegistrationToken(ctx context.Context, instance *garov1alpha1.GithubActionRunner, secret *corev1.Secret) error {
 	objectKey := r.getRegistrationSecretObjectKey(instance)
 	secret.GetObjectMeta().SetName(objectKey.Name)
 	secret.GetObjectMeta().SetNamespace(objectKey.Namespace)
-	apiToken, err := r.tokenForRef(ctx, instance)
+	credentials, err := r.credentialsForRef(ctx, instance)
 	if err != nil {
 		return err
 	}
 
-	regToken, err := r.GithubAPI.CreateRegistrationToken(ctx, instance.Spec.Organization, instance.Spec.Repository, apiToken)
+	regToken, err := r.GithubAPI.CreateRegistrationToken(ctx, instance.Spec.Organization, instance.Spec.Repository, credentials)
 	if err != nil {
 		return err
 	}
@@ -269,6 +418,10 @@ func (r *GithubActionRunnerReconciler) updateRegistrationToken(ctx context.Conte
 
 		return err
 	})
+	if err == nil {
+		tokenRefreshes.WithLabelValues(instance.Namespace, instance.Name).Inc()
+		instance.Status.RegistrationTokenExpiresAt = &metav1.Time{Time: regToken.ExpiresAt.Time}
+	}
 
 	return err
 }
@@ -287,37 +440,84 @@ func (r *GithubActionRunnerReconciler) addMetaData(instance *garov1alpha1.Github
 	return err
 }
 
-func (r *GithubActionRunnerReconciler) scaleUp(ctx context.Context, amount int, instance *garov1alpha1.GithubActionRunner) error {
+// scaleUp creates amount runner pods, or less if the quotas of the namespace do not allow for more, and returns how many were created
+func (r *GithubActionRunnerReconciler) scaleUp(ctx context.Context, amount int, instance *garov1alpha1.GithubActionRunner) (int, error) {
+	runnerSpec, err := r.runnerSpec(ctx, instance)
+	if err != nil {
+		return 0, err
+	}
+	quotaSpec, err := r.runnerPodSpec(ctx, instance)
+	if err != nil {
+		return 0, err
+	}
+	allowed, limitedBy, err := r.quotaAllowance(ctx, instance, amount, &quotaSpec)
+	if err != nil {
+		return 0, err
+	}
+	if allowed < amount {
+		message := fmt.Sprintf("Creating %d of %d runners, %s", allowed, amount, limitedBy)
+		logr.FromContextOrDiscard(ctx).Info("Throttled by quota", "message", message)
+		r.GetRecorder().Event(instance, corev1.EventTypeWarning, "QuotaThrottled", message)
+		setCondition(instance, quotaThrottledCondition, true, "QuotaExceeded", message)
+		amount = allowed
+	} else {
+		setCondition(instance, quotaThrottledCondition, false, "WithinQuota", "")
+	}
+
+	var slots []int
+	if usesSlots(instance) {
+		podList, err := r.listRelatedPods(ctx, instance)
+		if err != nil {
+			return 0, err
+		}
+		slots = freeSlots(podList.Items, amount)
+	}
+
 	for i := 0; i < amount; i++ {
 		pod := &corev1.Pod{
 			ObjectMeta: metav1.ObjectMeta{
 				GenerateName: fmt.Sprintf("%s-pod-", instance.Name),
 				Namespace:    instance.Namespace,
-				Labels:       instance.Spec.PodTemplateSpec.GetObjectMeta().GetLabels(),
-				Annotations:  instance.Spec.PodTemplateSpec.GetObjectMeta().GetAnnotations(),
+				Labels:       lo.Assign(runnerSpec.PodTemplateSpec.GetObjectMeta().GetLabels()),
+				Annotations:  runnerSpec.PodTemplateSpec.GetObjectMeta().GetAnnotations(),
 			},
 		}
-		result, err := controllerutil.CreateOrUpdate(ctx, r.GetClient(), pod, func() error {
-			pod.Spec = *instance.Spec.PodTemplateSpec.Spec.DeepCopy()
-
-			meta := pod.GetObjectMeta()
-			if err := r.addMetaData(instance, &meta); err != nil {
-				return err
+		spec, err := r.runnerPodSpec(ctx, instance)
+		if err != nil {
+			return i, err
+		}
+		if slots != nil {
+			pod.Labels[slotLabel] = strconv.Itoa(slots[i])
+			if instance.Spec.PodNaming == garov1alpha1.OrdinalPodNaming {
+				pod.GenerateName = ""
+				pod.Name = fmt.Sprintf("%s-%d", instance.Name, slots[i])
+			}
+			if err := r.ensureVolumeClaims(ctx, instance, slots[i], &spec); err != nil {
+				return i, err
 			}
+		}
+		pod.Spec = spec
 
-			util.AddFinalizer(pod, finalizer)
+		meta := pod.GetObjectMeta()
+		if err := r.addMetaData(instance, &meta); err != nil {
+			return i, err
+		}
+		util.AddFinalizer(pod, finalizer)
 
-			return nil
-		})
-		logr.FromContextOrDiscard(ctx).Info("Creating a new Pod", "Pod.Namespace", pod.Namespace, "Pod.Name", pod.Name, "result", result)
-		if err != nil {
-			return err
+		// a plain create, an ordinal name still taken by a pod missing from the cache must not be updated
+		if err := r.GetClient().Create(ctx, pod); err != nil {
+			return i, err
+		}
+		logr.FromContextOrDiscard(ctx).Info("Created a new Pod", "Pod.Namespace", pod.Namespace, "Pod.Name", pod.Name)
+
+		if err := r.createRunnerInstance(ctx, instance, pod); err != nil {
+			return i + 1, err
 		}
 
 		r.GetRecorder().Event(instance, corev1.EventTypeNormal, "Scaling", fmt.Sprintf("Created pod %s/%s", pod.Namespace, pod.Name))
 	}
 
-	return nil
+	return amount, nil
 }
 
 // listRelatedPods returns pods related to the GithubActionRunner
@@ -343,11 +543,18 @@ func (r *GithubActionRunnerReconciler) unregisterRunner(ctx context.Context, cr
 	if util.HasFinalizer(&pair.pod, finalizer) {
 		if pair.runner.GetName() != "" && pair.runner.GetID() != 0 {
 			logr.FromContextOrDiscard(ctx).Info("Unregistering runner", "name", pair.runner.GetName(), "id", pair.runner.GetID())
-			token, err := r.tokenForRef(ctx, cr)
+			credentials, err := r.credentialsForRef(ctx, cr)
 			if err != nil {
 				return err
 			}
-			if err = r.GithubAPI.UnregisterRunner(ctx, cr.Spec.Organization, cr.Spec.Repository, token, *pair.runner.ID); err != nil {
+			err = r.GithubAPI.UnregisterRunner(ctx, cr.Spec.Organization, cr.Spec.Repository, credentials, *pair.runner.ID)
+			if githubapi.IsNotFound(err) {
+				// removed meanwhile, e.g. an ephemeral runner after its job, or by hand
+				logr.FromContextOrDiscard(ctx).Info("Runner already unregistered", "name", pair.runner.GetName(), "id", pair.runner.GetID())
+				err = nil
+			}
+			if err != nil {
+				unregisterFailures.WithLabelValues(cr.Namespace, cr.Name).Inc()
 				return err
 			}
 		}
@@ -387,18 +594,33 @@ func (r *GithubActionRunnerReconciler) handleFinalization(ctx context.Context, c
 	return nil
 }
 
-// tokenForRef returns the token referenced from the GithubActionRunner Spec.TokenRef
-func (r *GithubActionRunnerReconciler) tokenForRef(ctx context.Context, cr *garov1alpha1.GithubActionRunner) (string, error) {
-	var secret corev1.Secret
-	if cr.Spec.TokenRef.Name != "" {
-		if err := r.GetClient().Get(ctx, client.ObjectKey{Name: cr.Spec.TokenRef.Name, Namespace: cr.Namespace}, &secret); err != nil {
-			return "", err
-		}
+// unregisterDeletedPods unregisters the runners of the pods being deleted, which a paused pool does not hold up.
+// GitHub is only called if there are such pods.
+func (r *GithubActionRunnerReconciler) unregisterDeletedPods(ctx context.Context, cr *garov1alpha1.GithubActionRunner) error {
+	podList, err := r.listRelatedPods(ctx, cr)
+	if err != nil {
+		return err
+	}
+	if !lo.ContainsBy(podList.Items, func(pod corev1.Pod) bool {
+		return !pod.DeletionTimestamp.IsZero() && util.HasFinalizer(&pod, finalizer)
+	}) {
+		return nil
+	}
 
-		return string(secret.Data[cr.Spec.TokenRef.Key]), nil
+	podRunnerPairs, err := r.getPodRunnerPairs(ctx, cr)
+	if err != nil {
+		return err
+	}
+	for _, pair := range podRunnerPairs.pairs {
+		if pair.pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if err := r.unregisterRunner(ctx, cr, pair); err != nil {
+			return err
+		}
 	}
 
-	return "", nil
+	return nil
 }
 
 // getPodRunnerPairs returns a struct podRunnerPairList with pods and runners
@@ -410,12 +632,12 @@ func (r *GithubActionRunnerReconciler) getPodRunnerPairs(ctx context.Context, cr
 		return podRunnerPairList, err
 	}
 
-	token, err := r.tokenForRef(ctx, cr)
+	credentials, err := r.credentialsForRef(ctx, cr)
 	if err != nil {
 		return podRunnerPairList, err
 	}
 
-	allRunners, err := r.GithubAPI.GetRunners(ctx, cr.Spec.Organization, cr.Spec.Repository, token)
+	allRunners, err := r.GithubAPI.GetRunners(ctx, cr.Spec.Organization, cr.Spec.Repository, credentials)
 	runners := lo.Filter(allRunners, func(runner *github.Runner, _ int) bool {
 		return strings.HasPrefix(runner.GetName(), cr.Name)
 	})
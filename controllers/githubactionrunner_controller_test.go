@@ -3,11 +3,15 @@ package controllers
 import (
 	"context"
 	"k8s.io/utils/ptr"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/evryfs/github-actions-runner-operator/controllers/githubapi"
 	"github.com/google/go-github/v59/github"
 	"github.com/gophercloud/gophercloud/testhelper"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/redhat-cop/operator-utils/pkg/util"
 	"github.com/stretchr/testify/mock"
 	v1 "k8s.io/api/core/v1"
@@ -16,29 +20,45 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-func (r *mockAPI) GetRunners(ctx context.Context, organization string, repository string, token string) ([]*github.Runner, error) {
-	args := r.Called(organization, repository, token)
+func (r *mockAPI) GetRunners(ctx context.Context, organization string, repository string, credentials githubapi.Credentials) ([]*github.Runner, error) {
+	args := r.Called(organization, repository, credentials)
 	return args.Get(0).([]*github.Runner), args.Error(1)
 }
 
-func (r *mockAPI) UnregisterRunner(ctx context.Context, organization string, repository string, token string, runnerID int64) error {
-	return nil
+func (r *mockAPI) UnregisterRunner(ctx context.Context, organization string, repository string, credentials githubapi.Credentials, runnerID int64) error {
+	return r.unregisterErr
 }
 
-func (r *mockAPI) CreateRegistrationToken(ctx context.Context, organization string, repository string, token string) (*github.RegistrationToken, error) {
+func (r *mockAPI) CreateRegistrationToken(ctx context.Context, organization string, repository string, credentials githubapi.Credentials) (*github.RegistrationToken, error) {
 	return &github.RegistrationToken{
 		Token:     github.String("sometoken"),
 		ExpiresAt: &github.Timestamp{},
 	}, nil
 }
 
+func (r *mockAPI) RateLimit(organization string, credentials githubapi.Credentials) (github.Rate, bool) {
+	return github.Rate{}, false
+}
+
+func (r *mockAPI) CheckCredentials(ctx context.Context, organization string, credentials githubapi.Credentials) (githubapi.CredentialStatus, error) {
+	return githubapi.CredentialStatus{}, nil
+}
+
+func (r *mockAPI) InvalidateCredentials(organization string, credentials githubapi.Credentials) {
+	r.Called(organization, credentials)
+}
+
 type mockAPI struct {
 	mock.Mock
+	// returned by UnregisterRunner
+	unregisterErr error
 }
 
 func TestGithubactionRunnerController(t *testing.T) {
@@ -56,7 +76,7 @@ func TestGithubactionRunnerController(t *testing.T) {
 	var mockResult []*github.Runner
 
 	mockAPI := new(mockAPI)
-	mockAPI.On("GetRunners", org, repo, token).Return(mockResult, nil).Once()
+	mockAPI.On("GetRunners", org, repo, githubapi.Credentials{Token: token}).Return(mockResult, nil).Once()
 
 	runner := &v1alpha1.GithubActionRunner{
 		ObjectMeta: metav1.ObjectMeta{
@@ -108,9 +128,9 @@ func TestGithubactionRunnerController(t *testing.T) {
 	ctx := context.TODO()
 
 	s := scheme.Scheme
-	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, runner)
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, runner, &v1alpha1.GithubActionRunnerInstance{}, &v1alpha1.GithubActionRunnerInstanceList{}, &v1alpha1.GithubActionRunnerQuotaList{})
 
-	cl := fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(objs...).WithStatusSubresource(runner).Build()
+	cl := fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(objs...).WithStatusSubresource(runner, &v1alpha1.GithubActionRunnerInstance{}).Build()
 
 	fakeRecorder := record.NewFakeRecorder(10)
 	r := &GithubActionRunnerReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, fakeRecorder, nil), Log: zap.New(), GithubAPI: mockAPI}
@@ -122,9 +142,15 @@ func TestGithubactionRunnerController(t *testing.T) {
 		},
 	}
 
+	scaleUpsBefore := testutil.ToFloat64(scaleUps.WithLabelValues(namespace, name, scaleReasonBelowMinimum))
+	tokenRefreshesBefore := testutil.ToFloat64(tokenRefreshes.WithLabelValues(namespace, name))
+
 	res, err := r.Reconcile(ctx, req)
 	testhelper.AssertNoErr(t, err)
 	testhelper.AssertEquals(t, false, res.Requeue)
+	testhelper.AssertEquals(t, scaleUpsBefore+2, testutil.ToFloat64(scaleUps.WithLabelValues(namespace, name, scaleReasonBelowMinimum)))
+	testhelper.AssertEquals(t, tokenRefreshesBefore+1, testutil.ToFloat64(tokenRefreshes.WithLabelValues(namespace, name)))
+	testhelper.AssertEquals(t, float64(2), testutil.ToFloat64(desiredRunners.WithLabelValues(namespace, name)))
 
 	podList := &v1.PodList{}
 	err = r.GetClient().List(ctx, podList)
@@ -142,6 +168,14 @@ func TestGithubactionRunnerController(t *testing.T) {
 	testhelper.AssertDeepEquals(t, expectedLabels, podObjectMeta.GetLabels())
 	testhelper.AssertDeepEquals(t, runner.Spec.PodTemplateSpec.GetObjectMeta().GetAnnotations(), podObjectMeta.GetAnnotations())
 
+	runnerInstance := &v1alpha1.GithubActionRunnerInstance{}
+	err = r.GetClient().Get(ctx, types.NamespacedName{Namespace: namespace, Name: podList.Items[0].Name}, runnerInstance)
+	testhelper.AssertNoErr(t, err)
+	testhelper.AssertEquals(t, name, runnerInstance.Spec.Pool)
+	testhelper.AssertEquals(t, v1alpha1.RunnerPending, runnerInstance.Status.Phase)
+	// the instance records the pool to unregister the runner once the pool is gone
+	testhelper.AssertDeepEquals(t, &v1alpha1.PoolRecord{UID: runner.UID, Organization: org, Repository: repo, TokenRef: &runner.Spec.TokenRef}, runnerInstance.Status.Pool)
+
 	// then scale down
 	mockResult = append(mockResult, &github.Runner{
 		ID:     ptr.To[int64](1),
@@ -156,7 +190,7 @@ func TestGithubactionRunnerController(t *testing.T) {
 		Status: ptr.To("online"),
 		Busy:   ptr.To(false),
 	})
-	mockAPI.On("GetRunners", org, repo, token).Return(mockResult, nil).Once()
+	mockAPI.On("GetRunners", org, repo, githubapi.Credentials{Token: token}).Return(mockResult, nil).Once()
 
 	err = r.GetClient().Get(ctx, req.NamespacedName, runner)
 	testhelper.AssertNoErr(t, err)
@@ -173,5 +207,189 @@ func TestGithubactionRunnerController(t *testing.T) {
 	testhelper.AssertNoErr(t, err)
 	testhelper.AssertEquals(t, runner.Spec.MinRunners, len(podList.Items))
 	testhelper.AssertEquals(t, numEvents+1, len(fakeRecorder.Events))
+
+	err = r.GetClient().Get(ctx, types.NamespacedName{Namespace: namespace, Name: podList.Items[0].Name}, runnerInstance)
+	testhelper.AssertNoErr(t, err)
+	testhelper.AssertEquals(t, v1alpha1.RunnerIdle, runnerInstance.Status.Phase)
+	testhelper.AssertEquals(t, true, runnerInstance.Status.RegisteredAt != nil)
+	testhelper.AssertEquals(t, true, runnerInstance.Status.RunnerID != 0)
 	mockAPI.AssertExpectations(t)
 }
+
+func TestAppCredentials(t *testing.T) {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "someApp"},
+		Data: map[string][]byte{
+			"GITHUB_APP_INTEGRATION_ID": []byte("1234\n"),
+			"GITHUB_APP_PRIVATE_KEY":    []byte("somePrivateKey"),
+			"installation":              []byte("5678"),
+		},
+	}
+
+	credentials, err := appCredentials(&v1alpha1.GithubAppSecretRef{Name: secret.Name}, secret.Name, secret.Data)
+	testhelper.AssertNoErr(t, err)
+	testhelper.AssertEquals(t, int64(1234), credentials.App.IntegrationID)
+	testhelper.AssertEquals(t, "somePrivateKey", string(credentials.App.PrivateKey))
+	testhelper.AssertEquals(t, int64(0), credentials.InstallationID)
+
+	credentials, err = appCredentials(&v1alpha1.GithubAppSecretRef{Name: secret.Name, InstallationIDKey: "installation"}, secret.Name, secret.Data)
+	testhelper.AssertNoErr(t, err)
+	testhelper.AssertEquals(t, int64(5678), credentials.InstallationID)
+
+	_, err = appCredentials(&v1alpha1.GithubAppSecretRef{Name: secret.Name, PrivateKeyKey: "missing"}, secret.Name, secret.Data)
+	testhelper.AssertErr(t, err)
+}
+
+func TestCredentialsForRef(t *testing.T) {
+	const namespace = "someNamespace"
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "someSecret", Namespace: namespace},
+		Data:       map[string][]byte{"GH_TOKEN": []byte("someToken\n")},
+	}
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{})
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(secret).Build()
+	r := &GithubActionRunnerReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, record.NewFakeRecorder(10), nil), Log: zap.New()}
+
+	runner := &v1alpha1.GithubActionRunner{ObjectMeta: metav1.ObjectMeta{Name: "somerunner", Namespace: namespace}}
+	runner.Spec.TokenRef = v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: secret.Name}, Key: "GH_TOKEN"}
+	credentials, err := r.credentialsForRef(context.TODO(), runner)
+	testhelper.AssertNoErr(t, err)
+	testhelper.AssertEquals(t, "someToken", credentials.Token)
+
+	runner.Spec.TokenRef.Key = "MISSING"
+	_, err = r.credentialsForRef(context.TODO(), runner)
+	testhelper.AssertErr(t, err)
+
+	runner.Spec.TokenRef.Name = "missing"
+	_, err = r.credentialsForRef(context.TODO(), runner)
+	testhelper.AssertErr(t, err)
+
+	// a reconcile resolves the credentials once, a rotation is seen by the next one
+	runner.Spec.TokenRef = v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: secret.Name}, Key: "GH_TOKEN"}
+	ctx := withResolvedCredentials(context.TODO())
+	_, err = r.credentialsForRef(ctx, runner)
+	testhelper.AssertNoErr(t, err)
+	secret.Data["GH_TOKEN"] = []byte("someRotatedToken")
+	testhelper.AssertNoErr(t, cl.Update(context.TODO(), secret))
+	credentials, err = r.credentialsForRef(ctx, runner)
+	testhelper.AssertNoErr(t, err)
+	testhelper.AssertEquals(t, "someToken", credentials.Token)
+	credentials, err = r.credentialsForRef(withResolvedCredentials(context.TODO()), runner)
+	testhelper.AssertNoErr(t, err)
+	testhelper.AssertEquals(t, "someRotatedToken", credentials.Token)
+}
+
+func TestCredentialsSecretHandler(t *testing.T) {
+	const namespace = "someNamespace"
+	const org = "SomeOrg"
+	oldSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "someSecret", Namespace: namespace},
+		Data:       map[string][]byte{"GH_TOKEN": []byte("someOldToken")},
+	}
+	newSecret := oldSecret.DeepCopy()
+	newSecret.Data["GH_TOKEN"] = []byte("someNewToken")
+	runner := &v1alpha1.GithubActionRunner{
+		ObjectMeta: metav1.ObjectMeta{Name: "somerunner", Namespace: namespace},
+		Spec: v1alpha1.GithubActionRunnerSpec{
+			Organization: org,
+			TokenRef:     v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: oldSecret.Name}, Key: "GH_TOKEN"},
+		},
+	}
+	otherRunner := &v1alpha1.GithubActionRunner{ObjectMeta: metav1.ObjectMeta{Name: "otherrunner", Namespace: namespace}}
+	// inherits the secret from its class
+	class := &v1alpha1.ClusterGithubActionRunnerClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "someclass"},
+		Spec: v1alpha1.ClusterGithubActionRunnerClassSpec{
+			TokenRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: oldSecret.Name}, Key: "GH_TOKEN"},
+		},
+	}
+	classedRunner := &v1alpha1.GithubActionRunner{
+		ObjectMeta: metav1.ObjectMeta{Name: "classedrunner", Namespace: namespace},
+		Spec:       v1alpha1.GithubActionRunnerSpec{Organization: org, ClassName: class.Name},
+	}
+	missingClassRunner := &v1alpha1.GithubActionRunner{
+		ObjectMeta: metav1.ObjectMeta{Name: "missingclassrunner", Namespace: namespace},
+		Spec:       v1alpha1.GithubActionRunnerSpec{Organization: org, ClassName: "missingclass"},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerList{},
+		&v1alpha1.ClusterGithubActionRunnerClass{}, &v1alpha1.ClusterGithubActionRunnerClassList{})
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(runner, otherRunner, class, classedRunner, missingClassRunner).
+		WithIndex(&v1alpha1.GithubActionRunner{}, credentialsSecretIndex, indexCredentialsSecret).
+		WithIndex(&v1alpha1.GithubActionRunner{}, runnerClassIndex, indexRunnerClass).
+		WithIndex(&v1alpha1.ClusterGithubActionRunnerClass{}, classCredentialsSecretIndex, indexClassCredentialsSecret).Build()
+	mockAPI := new(mockAPI)
+	mockAPI.On("InvalidateCredentials", org, githubapi.Credentials{Token: "someOldToken"}).Twice()
+	r := &GithubActionRunnerReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, record.NewFakeRecorder(10), nil), Log: zap.New(), GithubAPI: mockAPI}
+
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+	handler := r.credentialsSecretHandler()
+
+	// unchanged data, e.g. a label being added, is ignored
+	handler.Update(context.TODO(), event.UpdateEvent{ObjectOld: oldSecret, ObjectNew: oldSecret.DeepCopy()}, queue)
+	testhelper.AssertEquals(t, 0, queue.Len())
+
+	handler.Update(context.TODO(), event.UpdateEvent{ObjectOld: oldSecret, ObjectNew: newSecret}, queue)
+	testhelper.AssertEquals(t, 2, queue.Len())
+	item, _ := queue.Get()
+	testhelper.AssertEquals(t, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: runner.Name}}, item)
+	item, _ = queue.Get()
+	testhelper.AssertEquals(t, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: classedRunner.Name}}, item)
+	queue.Done(item)
+	mockAPI.AssertExpectations(t)
+
+	// the registration token secrets of the operator are ignored, even if named like a credentials secret
+	ownedSecret := newSecret.DeepCopy()
+	isController := true
+	ownedSecret.Data["GH_TOKEN"] = []byte("someRegistrationToken")
+	ownedSecret.OwnerReferences = []metav1.OwnerReference{{APIVersion: v1alpha1.GroupVersion.String(), Kind: "GithubActionRunner", Name: runner.Name, Controller: &isController}}
+	handler.Update(context.TODO(), event.UpdateEvent{ObjectOld: newSecret, ObjectNew: ownedSecret}, queue)
+	testhelper.AssertEquals(t, 0, queue.Len())
+}
+
+func TestRegistrationTokenRefresh(t *testing.T) {
+	const namespace = "someNamespace"
+	runner := &v1alpha1.GithubActionRunner{
+		ObjectMeta: metav1.ObjectMeta{Name: "somerunner", Namespace: namespace},
+		Spec: v1alpha1.GithubActionRunnerSpec{
+			Organization:                   "SomeOrg",
+			ReconciliationPeriod:           metav1.Duration{Duration: time.Hour},
+			RegistrationTokenRefreshMargin: metav1.Duration{Duration: 5 * time.Minute},
+		},
+	}
+	corrupt := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "somerunner-regtoken",
+			Namespace:   namespace,
+			Annotations: map[string]string{registrationTokenExpiresAtAnnotation: "garbage"},
+		},
+		Data: map[string][]byte{registrationTokenKey: []byte("someOldToken")},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{})
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(runner, corrupt).Build()
+	r := &GithubActionRunnerReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, record.NewFakeRecorder(10), nil), Log: zap.New(), GithubAPI: new(mockAPI)}
+
+	// a corrupt secret is regenerated rather than failing the reconcile
+	err := r.createOrUpdateRegistrationTokenSecret(context.TODO(), runner)
+	testhelper.AssertNoErr(t, err)
+	secret := &v1.Secret{}
+	err = cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: corrupt.Name}, secret)
+	testhelper.AssertNoErr(t, err)
+	_, err = strconv.ParseInt(secret.Annotations[registrationTokenExpiresAtAnnotation], 10, 64)
+	testhelper.AssertNoErr(t, err)
+	testhelper.AssertEquals(t, true, runner.Status.RegistrationTokenExpiresAt != nil)
+
+	// requeue in time to refresh the token
+	runner.Status.RegistrationTokenExpiresAt = &metav1.Time{Time: time.Now().Add(30 * time.Minute)}
+	testhelper.AssertEquals(t, true, requeueAfter(runner) <= 25*time.Minute && requeueAfter(runner) > 24*time.Minute)
+	runner.Status.RegistrationTokenExpiresAt = &metav1.Time{Time: time.Now().Add(2 * time.Minute)}
+	testhelper.AssertEquals(t, time.Second, requeueAfter(runner))
+	runner.Spec.ReconciliationPeriod = metav1.Duration{Duration: time.Minute}
+	runner.Status.RegistrationTokenExpiresAt = &metav1.Time{Time: time.Now().Add(30 * time.Minute)}
+	testhelper.AssertEquals(t, time.Minute, requeueAfter(runner))
+}
@@ -0,0 +1,94 @@
+package githubapi
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// credentialCheckInterval is how long the outcome of probing a credential is kept
+const credentialCheckInterval = 10 * time.Minute
+
+// CredentialStatus is what GitHub reported about a credential when probing it
+type CredentialStatus struct {
+	// Scopes of a classic personal access token, empty for fine-grained tokens and apps
+	Scopes []string
+	// ExpiresAt is when the token expires, nil if it does not or GitHub did not tell
+	ExpiresAt *time.Time
+}
+
+type credentialCheck struct {
+	status  CredentialStatus
+	err     error
+	checked time.Time
+}
+
+// credentialChecks keeps the outcome of probing credentials, so they are probed at most once per interval
+type credentialChecks struct {
+	sync.Mutex
+	byCredential map[string]credentialCheck
+}
+
+func newCredentialChecks() *credentialChecks {
+	return &credentialChecks{byCredential: make(map[string]credentialCheck)}
+}
+
+func (c *credentialChecks) get(credential string) (credentialCheck, bool) {
+	c.Lock()
+	defer c.Unlock()
+	check, found := c.byCredential[credential]
+	if found && time.Since(check.checked) > credentialCheckInterval {
+		return check, false
+	}
+
+	return check, found
+}
+
+func (c *credentialChecks) record(credential string, check credentialCheck) {
+	c.Lock()
+	defer c.Unlock()
+	c.byCredential[credential] = check
+}
+
+func (c *credentialChecks) invalidate(credential string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.byCredential, credential)
+}
+
+// CheckCredentials probes the credentials with an authenticated call that does not count against the rate limit
+func (r runnerAPI) CheckCredentials(ctx context.Context, organization string, credentials Credentials) (CredentialStatus, error) {
+	key := credentials.key(organization)
+	if check, found := r.credentialChecks.get(key); found {
+		return check.status, check.err
+	}
+
+	client, err := r.getClient(ctx, organization, credentials)
+	if err != nil {
+		return CredentialStatus{}, err
+	}
+
+	_, response, err := client.RateLimit.Get(ctx)
+	if err = r.afterCall(organization, credentials, response, err); err != nil && !isRejected(err) {
+		// transient errors and rate limits say nothing about the credential
+		return CredentialStatus{}, err
+	}
+
+	check := credentialCheck{err: err, checked: time.Now()}
+	if err == nil {
+		if scopes := response.Header.Get("X-OAuth-Scopes"); scopes != "" {
+			for _, scope := range strings.Split(scopes, ",") {
+				check.status.Scopes = append(check.status.Scopes, strings.TrimSpace(scope))
+			}
+		}
+		// installation tokens are short-lived and renewed by the client
+		if credentials.Token != "" && !response.TokenExpiration.IsZero() {
+			expiresAt := response.TokenExpiration.Time
+			check.status.ExpiresAt = &expiresAt
+		}
+	}
+	r.credentialChecks.record(key, check)
+
+	return check.status, check.err
+}
@@ -0,0 +1,46 @@
+package githubapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Credentials selects how to authenticate towards GitHub.
+// The token is used if set, else the app, else the operator wide app configured by environment variables.
+type Credentials struct {
+	// Token is a personal access token
+	Token string
+	// App are the credentials of a GitHub App installed in the organization
+	App *AppCredentials
+	// InstallationID pins the installation of the app, it is looked up by organization if zero
+	InstallationID int64
+}
+
+// AppCredentials identify a GitHub App
+type AppCredentials struct {
+	IntegrationID int64
+	PrivateKey    []byte
+}
+
+// key identifies the credential used for an organization without revealing it
+func (c Credentials) key(organization string) string {
+	switch {
+	case c.Token != "":
+		return "token:" + hash([]byte(c.Token))
+	case c.App != nil:
+		return "app:" + strconv.FormatInt(c.App.IntegrationID, 10) + ":" + organization
+	default:
+		return "app:" + organization
+	}
+}
+
+// appKey identifies an app and its private key, so a rotated key gets a new client creator
+func (a AppCredentials) appKey() string {
+	return strconv.FormatInt(a.IntegrationID, 10) + ":" + hash(a.PrivateKey)
+}
+
+func hash(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])[:12]
+}
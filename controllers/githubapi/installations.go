@@ -0,0 +1,116 @@
+package githubapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+	"github.com/google/go-github/v59/github"
+	"github.com/palantir/go-githubapp/githubapp"
+)
+
+// installationCacheTTLEnvVarName configures how long installation IDs looked up by organization are kept
+const installationCacheTTLEnvVarName = "GARO_INSTALLATION_CACHE_TTL"
+
+const defaultInstallationCacheTTL = time.Hour
+
+type installationKey struct {
+	// app is empty for the operator wide app
+	app string
+	// owner is lower cased as GitHub logins are case-insensitive
+	owner string
+}
+
+type cachedInstallation struct {
+	id      int64
+	fetched time.Time
+}
+
+// installationCache keeps the installation IDs of apps by owner, saving a lookup on every call to GitHub
+type installationCache struct {
+	sync.Mutex
+	ttl     time.Duration
+	byOwner map[installationKey]cachedInstallation
+}
+
+func newInstallationCache(ttl time.Duration) *installationCache {
+	return &installationCache{ttl: ttl, byOwner: make(map[installationKey]cachedInstallation)}
+}
+
+// get returns the installation ID of the app for owner, looking it up if unknown or expired
+func (c *installationCache) get(ctx context.Context, key installationKey, owner string, clientCreator githubapp.ClientCreator) (int64, error) {
+	c.Lock()
+	cached, found := c.byOwner[key]
+	c.Unlock()
+	if found && time.Since(cached.fetched) < c.ttl {
+		return cached.id, nil
+	}
+
+	client, err := clientCreator.NewAppClient()
+	if err != nil {
+		return 0, err
+	}
+
+	installation, err := githubapp.NewInstallationsService(client).GetByOwner(ctx, owner)
+	if err != nil {
+		return 0, err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.byOwner[key] = cachedInstallation{id: installation.ID, fetched: time.Now()}
+
+	return installation.ID, nil
+}
+
+func (c *installationCache) invalidate(key installationKey) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.byOwner, key)
+}
+
+func newInstallationKey(organization string, credentials Credentials) installationKey {
+	key := installationKey{owner: strings.ToLower(organization)}
+	if credentials.App != nil {
+		key.app = credentials.App.appKey()
+	}
+	return key
+}
+
+// isRejected tells whether GitHub rejected the credential, or the installation of the app was removed
+func isRejected(err error) bool {
+	var response *http.Response
+	var errorResponse *github.ErrorResponse
+	var tokenErr *ghinstallation.HTTPError
+	switch {
+	case errors.As(err, &errorResponse):
+		response = errorResponse.Response
+	case errors.As(err, &tokenErr):
+		// creating the installation token failed
+		response = tokenErr.Response
+	}
+	if response == nil {
+		return false
+	}
+
+	return response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusNotFound
+}
+
+// isInstallationGone tells whether the installation of the app was removed, which GitHub answers with 401 to calls
+// made with its token and with 404 when creating a token. A 404 from any other endpoint concerns the resource asked for
+func isInstallationGone(err error) bool {
+	var errorResponse *github.ErrorResponse
+	var tokenErr *ghinstallation.HTTPError
+	switch {
+	case errors.As(err, &errorResponse):
+		return errorResponse.Response != nil && errorResponse.Response.StatusCode == http.StatusUnauthorized
+	case errors.As(err, &tokenErr):
+		return isRejected(err)
+	}
+
+	return false
+}
@@ -0,0 +1,118 @@
+package githubapi
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v59/github"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// defaultRetryAfter is used for secondary rate limits where GitHub did not send a Retry-After header
+const defaultRetryAfter = time.Minute
+
+var (
+	rateLimitRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "garo",
+		Subsystem: "githubapi",
+		Name:      "ratelimit_remaining",
+		Help:      "Remaining GitHub API requests in the current rate limit window, by credential",
+	}, []string{"credential"})
+	rateLimitLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "garo",
+		Subsystem: "githubapi",
+		Name:      "ratelimit_limit",
+		Help:      "GitHub API requests allowed per rate limit window, by credential",
+	}, []string{"credential"})
+	rateLimitReset = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "garo",
+		Subsystem: "githubapi",
+		Name:      "ratelimit_reset_timestamp_seconds",
+		Help:      "When the current GitHub API rate limit window resets, by credential",
+	}, []string{"credential"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(rateLimitRemaining, rateLimitLimit, rateLimitReset)
+}
+
+// RateLimitedError is returned when GitHub refused a call because of its primary or secondary rate limits
+type RateLimitedError struct {
+	// RetryAfter is how long to wait before calling GitHub again with the same credential
+	RetryAfter time.Duration
+	err        error
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited by GitHub, retry after %s: %s", e.RetryAfter.Round(time.Second), e.err)
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	return e.err
+}
+
+// asRateLimitedError converts GitHub rate limit errors to a RateLimitedError, other errors are returned as is
+func asRateLimitedError(err error) error {
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return &RateLimitedError{RetryAfter: time.Until(rateLimitErr.Rate.Reset.Time), err: err}
+	}
+
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		retryAfter := defaultRetryAfter
+		if abuseErr.RetryAfter != nil {
+			retryAfter = *abuseErr.RetryAfter
+		}
+		return &RateLimitedError{RetryAfter: retryAfter, err: err}
+	}
+
+	return err
+}
+
+// rateLimits tracks the last known rate limit per credential
+type rateLimits struct {
+	sync.Mutex
+	byCredential map[string]github.Rate
+}
+
+func newRateLimits() *rateLimits {
+	return &rateLimits{byCredential: make(map[string]github.Rate)}
+}
+
+func (r *rateLimits) record(credential string, response *github.Response) {
+	if response == nil || response.Rate.Limit == 0 {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.byCredential[credential] = response.Rate
+	rateLimitRemaining.WithLabelValues(credential).Set(float64(response.Rate.Remaining))
+	rateLimitLimit.WithLabelValues(credential).Set(float64(response.Rate.Limit))
+	rateLimitReset.WithLabelValues(credential).Set(float64(response.Rate.Reset.Unix()))
+}
+
+func (r *rateLimits) get(credential string) (github.Rate, bool) {
+	r.Lock()
+	defer r.Unlock()
+	rate, found := r.byCredential[credential]
+	if found && time.Now().After(rate.Reset.Time) {
+		// window has been reset since last call
+		return rate, false
+	}
+
+	return rate, found
+}
+
+func (r *rateLimits) forget(credential string) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.byCredential, credential)
+	rateLimitRemaining.DeleteLabelValues(credential)
+	rateLimitLimit.DeleteLabelValues(credential)
+	rateLimitReset.DeleteLabelValues(credential)
+}
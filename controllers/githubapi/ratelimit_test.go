@@ -0,0 +1,53 @@
+package githubapi
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v59/github"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/ptr"
+)
+
+func TestAsRateLimitedError(t *testing.T) {
+	reset := time.Now().Add(time.Hour)
+	testCases := []struct {
+		err        error
+		limited    bool
+		retryAfter time.Duration
+	}{
+		{errors.New("some error"), false, 0},
+		{&github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: reset}}, Response: &http.Response{}}, true, time.Hour},
+		{&github.AbuseRateLimitError{RetryAfter: ptr.To(30 * time.Second), Response: &http.Response{}}, true, 30 * time.Second},
+		{&github.AbuseRateLimitError{Response: &http.Response{}}, true, defaultRetryAfter},
+	}
+
+	for _, tc := range testCases {
+		var rateLimited *RateLimitedError
+		assert.Equal(t, tc.limited, errors.As(asRateLimitedError(tc.err), &rateLimited))
+		if tc.limited {
+			assert.InDelta(t, tc.retryAfter.Seconds(), rateLimited.RetryAfter.Seconds(), 1)
+			assert.ErrorIs(t, rateLimited, tc.err)
+		}
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	limits := newRateLimits()
+	key := Credentials{Token: "someToken"}.key("someOrg")
+	assert.NotContains(t, key, "someToken")
+
+	_, found := limits.get(key)
+	assert.False(t, found)
+
+	limits.record(key, &github.Response{Rate: github.Rate{Limit: 5000, Remaining: 10, Reset: github.Timestamp{Time: time.Now().Add(time.Minute)}}})
+	rate, found := limits.get(key)
+	assert.True(t, found)
+	assert.Equal(t, 10, rate.Remaining)
+
+	limits.record(key, &github.Response{Rate: github.Rate{Limit: 5000, Remaining: 10, Reset: github.Timestamp{Time: time.Now().Add(-time.Minute)}}})
+	_, found = limits.get(key)
+	assert.False(t, found)
+}
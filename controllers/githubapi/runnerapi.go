@@ -2,8 +2,12 @@ package githubapi
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"sync"
 	"time"
 
+	"github.com/caitlinelfring/go-env-default"
 	prommetrics "github.com/deathowl/go-metrics-prometheus"
 	"github.com/google/go-github/v59/github"
 	"github.com/gregjones/httpcache"
@@ -14,16 +18,34 @@ import (
 
 // IRunnerAPI is a service towards GitHubs runners
 type IRunnerAPI interface {
-	GetRunners(ctx context.Context, organization string, repository string, token string) ([]*github.Runner, error)
-	UnregisterRunner(ctx context.Context, organization string, repository string, token string, runnerID int64) error
-	CreateRegistrationToken(ctx context.Context, organization string, repository string, token string) (*github.RegistrationToken, error)
+	GetRunners(ctx context.Context, organization string, repository string, credentials Credentials) ([]*github.Runner, error)
+	UnregisterRunner(ctx context.Context, organization string, repository string, credentials Credentials, runnerID int64) error
+	CreateRegistrationToken(ctx context.Context, organization string, repository string, credentials Credentials) (*github.RegistrationToken, error)
+	RateLimit(organization string, credentials Credentials) (github.Rate, bool)
+	CheckCredentials(ctx context.Context, organization string, credentials Credentials) (CredentialStatus, error)
+	InvalidateCredentials(organization string, credentials Credentials)
 }
 
 type runnerAPI struct {
-	clientCreator githubapp.ClientCreator
+	clientCreator    githubapp.ClientCreator
+	config           githubapp.Config
+	options          []githubapp.ClientOption
+	appCreators      *appClientCreators
+	v3APIURL         string
+	rateLimits       *rateLimits
+	runners          *runnerCache
+	installations    *installationCache
+	credentialChecks *credentialChecks
+}
+
+// appClientCreators holds a client creator per GitHub App referenced from a CR
+type appClientCreators struct {
+	sync.Mutex
+	byApp map[string]githubapp.ClientCreator
 }
 
 // NewRunnerAPI gets a new instance of the API.
+// GitHub Enterprise Server is supported by setting GITHUB_V3_API_URL.
 func NewRunnerAPI() (runnerAPI, error) {
 	config := githubapp.Config{
 		V3APIURL: "https://api.github.com",
@@ -35,45 +57,119 @@ func NewRunnerAPI() (runnerAPI, error) {
 	go promClient.UpdatePrometheusMetrics()
 
 	config.SetValuesFromEnv("")
-	clientCreator, err := githubapp.NewDefaultCachingClientCreator(config,
+
+	return newRunnerAPI(config,
+		env.GetDurationDefault(runnerCacheTTLEnvVarName, defaultRunnerCacheTTL),
+		env.GetDurationDefault(installationCacheTTLEnvVarName, defaultInstallationCacheTTL),
 		githubapp.WithClientUserAgent("evryfs/garo"),
 		githubapp.WithClientCaching(true, func() httpcache.Cache { return httpcache.NewMemoryCache() }),
 		githubapp.WithClientMiddleware(githubapp.ClientMetrics(registry)),
 	)
+}
+
+func newRunnerAPI(config githubapp.Config, runnerCacheTTL time.Duration, installationCacheTTL time.Duration, options ...githubapp.ClientOption) (runnerAPI, error) {
+	clientCreator, err := githubapp.NewDefaultCachingClientCreator(config, options...)
 
 	return runnerAPI{
-		clientCreator: clientCreator,
+		clientCreator:    clientCreator,
+		config:           config,
+		options:          options,
+		appCreators:      &appClientCreators{byApp: make(map[string]githubapp.ClientCreator)},
+		v3APIURL:         config.V3APIURL,
+		rateLimits:       newRateLimits(),
+		runners:          newRunnerCache(runnerCacheTTL),
+		installations:    newInstallationCache(installationCacheTTL),
+		credentialChecks: newCredentialChecks(),
 	}, err
 }
 
-func (r runnerAPI) getClient(ctx context.Context, organization string, token string) (*github.Client, error) {
-	if token != "" {
-		return r.clientCreator.NewTokenClient(token)
+// appClientCreator returns the client creator for the app, creating it on first use
+func (r runnerAPI) appClientCreator(app AppCredentials) (githubapp.ClientCreator, error) {
+	r.appCreators.Lock()
+	defer r.appCreators.Unlock()
+
+	key := app.appKey()
+	if clientCreator, found := r.appCreators.byApp[key]; found {
+		return clientCreator, nil
 	}
 
-	client, err := r.clientCreator.NewAppClient()
+	config := r.config
+	config.App.IntegrationID = app.IntegrationID
+	config.App.PrivateKey = string(app.PrivateKey)
+	clientCreator, err := githubapp.NewDefaultCachingClientCreator(config, r.options...)
 	if err != nil {
 		return nil, err
 	}
+	r.appCreators.byApp[key] = clientCreator
 
-	installationsService := githubapp.NewInstallationsService(client)
-	installation, err := installationsService.GetByOwner(ctx, organization)
-	if err != nil {
-		return nil, err
+	return clientCreator, nil
+}
+
+func (r runnerAPI) getClient(ctx context.Context, organization string, credentials Credentials) (*github.Client, error) {
+	if credentials.Token != "" {
+		return r.clientCreator.NewTokenClient(credentials.Token)
+	}
+
+	clientCreator := r.clientCreator
+	if credentials.App != nil {
+		var err error
+		if clientCreator, err = r.appClientCreator(*credentials.App); err != nil {
+			return nil, err
+		}
+	}
+
+	installationID := credentials.InstallationID
+	if installationID == 0 {
+		var err error
+		if installationID, err = r.installations.get(ctx, newInstallationKey(organization, credentials), organization, clientCreator); err != nil {
+			return nil, err
+		}
 	}
 
-	return r.clientCreator.NewInstallationClient(installation.ID)
+	return clientCreator.NewInstallationClient(installationID)
 }
 
-// Return all runners for the org
-func (r runnerAPI) GetRunners(ctx context.Context, organization string, repository string, token string) ([]*github.Runner, error) {
-	client, err := r.getClient(ctx, organization, token)
+// afterCall records the rate limit reported by GitHub and translates the error of a call
+func (r runnerAPI) afterCall(organization string, credentials Credentials, response *github.Response, err error) error {
+	r.rateLimits.record(credentials.key(organization), response)
+	if credentials.Token == "" && credentials.InstallationID == 0 && isInstallationGone(err) {
+		// the app may have been reinstalled, look up the installation again on the next call
+		r.installations.invalidate(newInstallationKey(organization, credentials))
+	}
+
+	return asRateLimitedError(err)
+}
+
+// IsNotFound tells if GitHub answered a call with 404, like when removing a runner that is not registered anymore
+func IsNotFound(err error) bool {
+	var errorResponse *github.ErrorResponse
+	return errors.As(err, &errorResponse) && errorResponse.Response != nil && errorResponse.Response.StatusCode == http.StatusNotFound
+}
+
+func (r runnerAPI) runnerCacheKey(organization string, repository string, credentials Credentials) runnerCacheKey {
+	return runnerCacheKey{
+		endpoint:     r.v3APIURL,
+		organization: organization,
+		repository:   repository,
+		credential:   credentials.key(organization),
+	}
+}
+
+// Return all runners for the org, shared with other pools listing the same org or repository for a short while
+func (r runnerAPI) GetRunners(ctx context.Context, organization string, repository string, credentials Credentials) ([]*github.Runner, error) {
+	return r.runners.get(r.runnerCacheKey(organization, repository, credentials), func() ([]*github.Runner, error) {
+		return r.listRunners(ctx, organization, repository, credentials)
+	})
+}
+
+func (r runnerAPI) listRunners(ctx context.Context, organization string, repository string, credentials Credentials) ([]*github.Runner, error) {
+	client, err := r.getClient(ctx, organization, credentials)
 	if err != nil {
 		return nil, err
 	}
 
 	var allRunners []*github.Runner
-	opts := &github.ListOptions{PerPage: 30}
+	opts := &github.ListOptions{PerPage: 100}
 
 	for {
 		var runners *github.Runners
@@ -85,7 +181,7 @@ func (r runnerAPI) GetRunners(ctx context.Context, organization string, reposito
 		} else {
 			runners, response, err = client.Actions.ListOrganizationRunners(ctx, organization, opts)
 		}
-		if err != nil {
+		if err = r.afterCall(organization, credentials, response, err); err != nil {
 			return allRunners, err
 		}
 
@@ -99,32 +195,57 @@ func (r runnerAPI) GetRunners(ctx context.Context, organization string, reposito
 	return allRunners, nil
 }
 
-func (r runnerAPI) UnregisterRunner(ctx context.Context, organization string, repository string, token string, runnerID int64) error {
-	client, err := r.getClient(ctx, organization, token)
+func (r runnerAPI) UnregisterRunner(ctx context.Context, organization string, repository string, credentials Credentials, runnerID int64) error {
+	client, err := r.getClient(ctx, organization, credentials)
 	if err != nil {
 		return err
 	}
 
+	var response *github.Response
 	if repository != "" {
-		_, err := client.Actions.RemoveRunner(ctx, organization, repository, runnerID)
-		return err
+		response, err = client.Actions.RemoveRunner(ctx, organization, repository, runnerID)
+	} else {
+		response, err = client.Actions.RemoveOrganizationRunner(ctx, organization, runnerID)
 	}
-	_, err = client.Actions.RemoveOrganizationRunner(ctx, organization, runnerID)
+	r.runners.invalidate(r.runnerCacheKey(organization, repository, credentials))
 
-	return err
+	return r.afterCall(organization, credentials, response, err)
 }
 
-func (r runnerAPI) CreateRegistrationToken(ctx context.Context, organization string, repository string, token string) (*github.RegistrationToken, error) {
-	client, err := r.getClient(ctx, organization, token)
+func (r runnerAPI) CreateRegistrationToken(ctx context.Context, organization string, repository string, credentials Credentials) (*github.RegistrationToken, error) {
+	client, err := r.getClient(ctx, organization, credentials)
 	if err != nil {
 		return nil, err
 	}
 
+	var regToken *github.RegistrationToken
+	var response *github.Response
 	if repository != "" {
-		regToken, _, err := client.Actions.CreateRegistrationToken(ctx, organization, repository)
-		return regToken, err
+		regToken, response, err = client.Actions.CreateRegistrationToken(ctx, organization, repository)
+	} else {
+		regToken, response, err = client.Actions.CreateOrganizationRegistrationToken(ctx, organization)
 	}
+	// runners registering with the new token should show up on the next listing
+	r.runners.invalidate(r.runnerCacheKey(organization, repository, credentials))
+
+	return regToken, r.afterCall(organization, credentials, response, err)
+}
+
+// RateLimit returns the last known rate limit of the credential used for the organization, false if unknown
+func (r runnerAPI) RateLimit(organization string, credentials Credentials) (github.Rate, bool) {
+	return r.rateLimits.get(credentials.key(organization))
+}
 
-	regToken, _, err := client.Actions.CreateOrganizationRegistrationToken(ctx, organization)
-	return regToken, err
+// InvalidateCredentials drops everything cached for credentials that are no longer used, like a rotated token
+func (r runnerAPI) InvalidateCredentials(organization string, credentials Credentials) {
+	key := credentials.key(organization)
+	r.credentialChecks.invalidate(key)
+	r.runners.invalidateCredential(key)
+	r.rateLimits.forget(key)
+	if credentials.App != nil {
+		r.installations.invalidate(newInstallationKey(organization, credentials))
+		r.appCreators.Lock()
+		defer r.appCreators.Unlock()
+		delete(r.appCreators.byApp, credentials.App.appKey())
+	}
 }
@@ -0,0 +1,154 @@
+package githubapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/palantir/go-githubapp/githubapp"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGitHub serves the GitHub endpoints used by the runner API and counts installation lookups and probes
+type fakeGitHub struct {
+	lookups      atomic.Int32
+	probes       atomic.Int32
+	unauthorized atomic.Bool
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == "/orgs/someOrg/installation":
+		f.lookups.Add(1)
+		fmt.Fprint(w, `{"id": 42}`)
+	case req.Method == http.MethodPost && req.URL.Path == "/app/installations/42/access_tokens":
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": "someInstallationToken", "expires_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+	case req.Method == http.MethodGet && req.URL.Path == "/orgs/someOrg/actions/runners":
+		if f.unauthorized.Swap(false) {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message": "Bad credentials"}`)
+			return
+		}
+		fmt.Fprint(w, `{"total_count": 1, "runners": [{"id": 1, "name": "somerunner", "status": "online"}]}`)
+	case req.Method == http.MethodDelete && req.URL.Path == "/orgs/someOrg/actions/runners/1":
+		w.WriteHeader(http.StatusNoContent)
+	case req.Method == http.MethodGet && req.URL.Path == "/rate_limit":
+		f.probes.Add(1)
+		if !strings.HasSuffix(req.Header.Get("Authorization"), " someToken") {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message": "Bad credentials"}`)
+			return
+		}
+		w.Header().Set("X-OAuth-Scopes", "repo, admin:org")
+		w.Header().Set("GitHub-Authentication-Token-Expiration", "2030-01-02 03:04:05 UTC")
+		fmt.Fprint(w, `{"resources": {}}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Not Found"}`)
+	}
+}
+
+func newTestRunnerAPI(t *testing.T, url string) runnerAPI {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	config := githubapp.Config{V3APIURL: url, V4APIURL: url}
+	config.App.IntegrationID = 1
+	config.App.PrivateKey = string(privateKey)
+	api, err := newRunnerAPI(config, 0, time.Hour)
+	assert.NoError(t, err)
+
+	return api
+}
+
+func TestInstallationCache(t *testing.T) {
+	fake := &fakeGitHub{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	api := newTestRunnerAPI(t, server.URL)
+	ctx := context.TODO()
+
+	for i := 0; i < 3; i++ {
+		runners, err := api.GetRunners(ctx, "someOrg", "", Credentials{})
+		assert.NoError(t, err)
+		assert.Len(t, runners, 1)
+	}
+	assert.Equal(t, int32(1), fake.lookups.Load())
+
+	// a revoked installation is looked up again
+	fake.unauthorized.Store(true)
+	_, err := api.GetRunners(ctx, "someOrg", "", Credentials{})
+	assert.Error(t, err)
+	_, err = api.GetRunners(ctx, "someOrg", "", Credentials{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), fake.lookups.Load())
+
+	// removing a runner that is not registered anymore keeps the installation
+	err = api.UnregisterRunner(ctx, "someOrg", "", Credentials{}, 2)
+	assert.True(t, IsNotFound(err))
+	_, err = api.GetRunners(ctx, "someOrg", "", Credentials{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), fake.lookups.Load())
+}
+
+func TestPinnedInstallation(t *testing.T) {
+	fake := &fakeGitHub{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	api := newTestRunnerAPI(t, server.URL)
+
+	runners, err := api.GetRunners(context.TODO(), "someOrg", "", Credentials{InstallationID: 42})
+	assert.NoError(t, err)
+	assert.Len(t, runners, 1)
+	assert.Equal(t, int32(0), fake.lookups.Load())
+}
+
+func TestCheckCredentials(t *testing.T) {
+	fake := &fakeGitHub{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	api := newTestRunnerAPI(t, server.URL)
+	ctx := context.TODO()
+
+	for i := 0; i < 2; i++ {
+		status, err := api.CheckCredentials(ctx, "someOrg", Credentials{Token: "someToken"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"repo", "admin:org"}, status.Scopes)
+		assert.Equal(t, time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC), status.ExpiresAt.UTC())
+	}
+	assert.Equal(t, int32(1), fake.probes.Load())
+
+	_, err := api.CheckCredentials(ctx, "someOrg", Credentials{Token: "someRevokedToken"})
+	assert.Error(t, err)
+	_, err = api.CheckCredentials(ctx, "someOrg", Credentials{Token: "someRevokedToken"})
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), fake.probes.Load())
+}
+
+func TestUnregisterRunner(t *testing.T) {
+	fake := &fakeGitHub{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	api := newTestRunnerAPI(t, server.URL)
+	ctx := context.TODO()
+
+	assert.NoError(t, api.UnregisterRunner(ctx, "someOrg", "", Credentials{InstallationID: 42}, 1))
+
+	// a runner that is not registered anymore
+	err := api.UnregisterRunner(ctx, "someOrg", "", Credentials{InstallationID: 42}, 2)
+	assert.Error(t, err)
+	assert.True(t, IsNotFound(err))
+	assert.False(t, IsNotFound(nil))
+}
@@ -0,0 +1,102 @@
+package githubapi
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v59/github"
+	"golang.org/x/sync/singleflight"
+)
+
+// runnerCacheTTLEnvVarName configures how long a runner listing is shared before GitHub is asked again
+const runnerCacheTTLEnvVarName = "GARO_RUNNER_CACHE_TTL"
+
+const defaultRunnerCacheTTL = 10 * time.Second
+
+// runnerCacheKey identifies a runner listing, pools with the same key share the listing
+type runnerCacheKey struct {
+	endpoint     string
+	organization string
+	repository   string
+	credential   string
+}
+
+func (k runnerCacheKey) String() string {
+	return k.endpoint + "|" + k.organization + "|" + k.repository + "|" + k.credential
+}
+
+type runnerSnapshot struct {
+	runners []*github.Runner
+	fetched time.Time
+}
+
+// runnerCache shares runner listings between reconciles of pools targeting the same organization or repository.
+// Concurrent fetches of the same listing are collapsed into one call to GitHub.
+type runnerCache struct {
+	sync.Mutex
+	ttl       time.Duration
+	snapshots map[runnerCacheKey]runnerSnapshot
+	// generations are bumped on invalidation so that fetches started before it are not stored
+	generations map[runnerCacheKey]uint64
+	group       singleflight.Group
+}
+
+func newRunnerCache(ttl time.Duration) *runnerCache {
+	return &runnerCache{
+		ttl:         ttl,
+		snapshots:   make(map[runnerCacheKey]runnerSnapshot),
+		generations: make(map[runnerCacheKey]uint64),
+	}
+}
+
+// get returns the cached listing for key if still fresh, otherwise fetches it. Returned runners must not be modified.
+func (c *runnerCache) get(key runnerCacheKey, fetch func() ([]*github.Runner, error)) ([]*github.Runner, error) {
+	c.Lock()
+	snapshot, found := c.snapshots[key]
+	generation := c.generations[key]
+	c.Unlock()
+	if found && time.Since(snapshot.fetched) < c.ttl {
+		return snapshot.runners, nil
+	}
+
+	runners, err, _ := c.group.Do(key.String(), func() (interface{}, error) {
+		runners, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+
+		c.Lock()
+		defer c.Unlock()
+		if c.generations[key] == generation {
+			c.snapshots[key] = runnerSnapshot{runners: runners, fetched: time.Now()}
+		}
+		return runners, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return runners.([]*github.Runner), nil
+}
+
+// invalidate drops the listing for key, the next get fetches it from GitHub
+func (c *runnerCache) invalidate(key runnerCacheKey) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.snapshots, key)
+	c.generations[key]++
+	c.group.Forget(key.String())
+}
+
+// invalidateCredential drops all listings fetched with credential
+func (c *runnerCache) invalidateCredential(credential string) {
+	c.Lock()
+	defer c.Unlock()
+	for key := range c.snapshots {
+		if key.credential == credential {
+			delete(c.snapshots, key)
+			c.generations[key]++
+			c.group.Forget(key.String())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package githubapi
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v59/github"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/ptr"
+)
+
+func TestRunnerCache(t *testing.T) {
+	cache := newRunnerCache(time.Minute)
+	key := runnerCacheKey{endpoint: "https://api.github.com", organization: "someOrg", credential: Credentials{Token: "someToken"}.key("someOrg")}
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	fetch := func() ([]*github.Runner, error) {
+		fetches.Add(1)
+		<-release
+		return []*github.Runner{{ID: ptr.To(int64(1))}}, nil
+	}
+
+	// concurrent reconciles share a single call
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runners, err := cache.get(key, fetch)
+			assert.NoError(t, err)
+			assert.Len(t, runners, 1)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// fresh listings are served from the cache
+	_, err := cache.get(key, fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// other repositories are listed separately
+	_, err = cache.get(runnerCacheKey{endpoint: key.endpoint, organization: key.organization, repository: "someRepo", credential: key.credential}, fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	cache.invalidate(key)
+	_, err = cache.get(key, fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), fetches.Load())
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricsNamespace = "garo"
+const poolSubsystem = "pool"
+
+// reasons recorded on the scale up/down counters
+const (
+	scaleReasonBelowMinimum = "BelowMinimum"
+	scaleReasonAllBusy      = "AllBusy"
+	scaleReasonAboveMaximum = "AboveMaximum"
+	scaleReasonIdle         = "Idle"
+	scaleReasonDrain        = "Drain"
+)
+
+var poolLabels = []string{"namespace", "name"}
+
+var (
+	desiredRunners = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: poolSubsystem,
+		Name:      "desired_runners",
+		Help:      "Number of runners the pool is scaling towards",
+	}, poolLabels)
+	currentRunners = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: poolSubsystem,
+		Name:      "current_runners",
+		Help:      "Number of runner pods in the pool",
+	}, poolLabels)
+	idleRunners = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: poolSubsystem,
+		Name:      "idle_runners",
+		Help:      "Number of registered runners in the pool not executing a job",
+	}, poolLabels)
+	busyRunners = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: poolSubsystem,
+		Name:      "busy_runners",
+		Help:      "Number of registered runners in the pool executing a job",
+	}, poolLabels)
+	scaleUps = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: poolSubsystem,
+		Name:      "scale_up_total",
+		Help:      "Number of runners created, by reason",
+	}, append(poolLabels, "reason"))
+	scaleDowns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: poolSubsystem,
+		Name:      "scale_down_total",
+		Help:      "Number of runners removed, by reason",
+	}, append(poolLabels, "reason"))
+	registrationLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: poolSubsystem,
+		Name:      "registration_latency_seconds",
+		Help:      "Time from runner pod creation until the runner is seen registered at GitHub",
+		Buckets:   prometheus.ExponentialBuckets(5, 2, 10),
+	}, poolLabels)
+	unregisterFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: poolSubsystem,
+		Name:      "unregister_failures_total",
+		Help:      "Number of failed attempts to unregister a runner at GitHub",
+	}, poolLabels)
+	outOfSyncDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: poolSubsystem,
+		Name:      "out_of_sync_seconds",
+		Help:      "How long pods and runners registered at GitHub have been out of sync, 0 when in sync",
+	}, poolLabels)
+	tokenRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: poolSubsystem,
+		Name:      "registration_token_refreshes_total",
+		Help:      "Number of times the registration token of the pool was refreshed",
+	}, poolLabels)
+	jobQueueWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: poolSubsystem,
+		Name:      "job_queue_wait_seconds",
+		Help:      "Time from a job being queued until a runner of the pool started it",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 14),
+	}, poolLabels)
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: poolSubsystem,
+		Name:      "job_duration_seconds",
+		Help:      "Time from a runner of the pool starting a job until it completed",
+		Buckets:   prometheus.ExponentialBuckets(10, 2, 12),
+	}, poolLabels)
+	jobsServed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: poolSubsystem,
+		Name:      "jobs_total",
+		Help:      "Number of jobs completed by runners of the pool, by conclusion",
+	}, append(poolLabels, "conclusion"))
+)
+
+var poolCollectors = []*prometheus.MetricVec{
+	desiredRunners.MetricVec,
+	currentRunners.MetricVec,
+	idleRunners.MetricVec,
+	busyRunners.MetricVec,
+	scaleUps.MetricVec,
+	scaleDowns.MetricVec,
+	registrationLatency.MetricVec,
+	unregisterFailures.MetricVec,
+	outOfSyncDuration.MetricVec,
+	tokenRefreshes.MetricVec,
+	jobQueueWait.MetricVec,
+	jobDuration.MetricVec,
+	jobsServed.MetricVec,
+}
+
+func init() {
+	metrics.Registry.MustRegister(desiredRunners, currentRunners, idleRunners, busyRunners, scaleUps, scaleDowns,
+		registrationLatency, unregisterFailures, outOfSyncDuration, tokenRefreshes, jobQueueWait, jobDuration, jobsServed)
+}
+
+// outOfSync keeps track of when pools were first seen out of sync
+var outOfSync = struct {
+	sync.Mutex
+	since map[types.NamespacedName]time.Time
+}{since: make(map[types.NamespacedName]time.Time)}
+
+// recordPoolState updates the gauges describing the current state of a pool
+func recordPoolState(pool types.NamespacedName, podRunnerPairs podRunnerPairList, desired int) {
+	labels := prometheus.Labels{"namespace": pool.Namespace, "name": pool.Name}
+	desiredRunners.With(labels).Set(float64(desired))
+	currentRunners.With(labels).Set(float64(podRunnerPairs.numPods()))
+	idleRunners.With(labels).Set(float64(podRunnerPairs.numIdle()))
+	busyRunners.With(labels).Set(float64(podRunnerPairs.numBusy()))
+
+	outOfSync.Lock()
+	defer outOfSync.Unlock()
+	if podRunnerPairs.inSync() {
+		delete(outOfSync.since, pool)
+		outOfSyncDuration.With(labels).Set(0)
+		return
+	}
+	since, found := outOfSync.since[pool]
+	if !found {
+		since = time.Now()
+		outOfSync.since[pool] = since
+	}
+	outOfSyncDuration.With(labels).Set(time.Since(since).Seconds())
+}
+
+// deletePoolMetrics removes all series of a pool that no longer exists
+func deletePoolMetrics(pool types.NamespacedName) {
+	labels := prometheus.Labels{"namespace": pool.Namespace, "name": pool.Name}
+	for _, collector := range poolCollectors {
+		collector.DeletePartialMatch(labels)
+	}
+
+	outOfSync.Lock()
+	defer outOfSync.Unlock()
+	delete(outOfSync.since, pool)
+}
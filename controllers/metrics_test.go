@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/google/go-github/v59/github"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestRecordPoolState(t *testing.T) {
+	pool := types.NamespacedName{Namespace: "metricsNamespace", Name: "statepool"}
+	podList := &corev1.PodList{Items: []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "statepool-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "statepool-2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "statepool-3"}},
+	}}
+	runners := []*github.Runner{
+		{ID: github.Int64(1), Name: github.String("statepool-1"), Busy: github.Bool(true)},
+		{ID: github.Int64(2), Name: github.String("statepool-2"), Busy: github.Bool(false)},
+	}
+
+	recordPoolState(pool, from(podList, runners), 4)
+	assert.Equal(t, float64(4), testutil.ToFloat64(desiredRunners.WithLabelValues(pool.Namespace, pool.Name)))
+	assert.Equal(t, float64(3), testutil.ToFloat64(currentRunners.WithLabelValues(pool.Namespace, pool.Name)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(idleRunners.WithLabelValues(pool.Namespace, pool.Name)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(busyRunners.WithLabelValues(pool.Namespace, pool.Name)))
+	assert.Contains(t, outOfSync.since, pool)
+
+	runners = append(runners, &github.Runner{ID: github.Int64(3), Name: github.String("statepool-3"), Busy: github.Bool(false)})
+	recordPoolState(pool, from(podList, runners), 3)
+	assert.Equal(t, float64(2), testutil.ToFloat64(idleRunners.WithLabelValues(pool.Namespace, pool.Name)))
+	assert.Zero(t, testutil.ToFloat64(outOfSyncDuration.WithLabelValues(pool.Namespace, pool.Name)))
+	assert.NotContains(t, outOfSync.since, pool)
+
+	scaleUps.WithLabelValues(pool.Namespace, pool.Name, scaleReasonAllBusy).Inc()
+	deletePoolMetrics(pool)
+	assert.False(t, desiredRunners.DeleteLabelValues(pool.Namespace, pool.Name))
+	assert.False(t, busyRunners.DeleteLabelValues(pool.Namespace, pool.Name))
+	assert.False(t, scaleUps.DeleteLabelValues(pool.Namespace, pool.Name, scaleReasonAllBusy))
+}
+
+func TestPoolMetrics(t *testing.T) {
+	ctx := context.TODO()
+	runner := testRunner()
+	runner.Name = "metricspool"
+	runner.Namespace = "metricsNamespace"
+	runner.UID = "metricsUID"
+	runner.TypeMeta = metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "GithubActionRunner"}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerList{}, &v1alpha1.GithubActionRunnerInstance{}, &v1alpha1.GithubActionRunnerInstanceList{}, &v1alpha1.GithubActionRunnerQuota{}, &v1alpha1.GithubActionRunnerQuotaList{})
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(runner).WithStatusSubresource(&v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerInstance{}).Build()
+	api := &mockAPI{unregisterErr: errors.New("runner is busy")}
+	r := &GithubActionRunnerReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, record.NewFakeRecorder(20), nil), Log: zap.New(), GithubAPI: api}
+
+	assert.Equal(t, 2, lo.Must(r.scaleUp(ctx, 2, runner)))
+	pods := lo.Must(r.listRelatedPods(ctx, runner))
+	pair := podRunnerPair{pod: pods.Items[0], runner: github.Runner{ID: github.Int64(1), Name: github.String(pods.Items[0].Name)}}
+
+	// a runner failing to unregister is counted and not removed
+	assert.False(t, lo.Must(r.removeIdleRunner(ctx, runner, pair, scaleReasonIdle)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(unregisterFailures.WithLabelValues(runner.Namespace, runner.Name)))
+
+	api.unregisterErr = nil
+	assert.True(t, lo.Must(r.removeIdleRunner(ctx, runner, pair, scaleReasonIdle)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(scaleDowns.WithLabelValues(runner.Namespace, runner.Name, scaleReasonIdle)))
+
+	// a paused pool records the pods it has and the runners as last observed
+	instance := &v1alpha1.GithubActionRunnerInstance{}
+	assert.NoError(t, cl.Get(ctx, types.NamespacedName{Namespace: runner.Namespace, Name: pods.Items[1].Name}, instance))
+	instance.Status.RunnerID = 2
+	instance.Status.RunnerName = pods.Items[1].Name
+	instance.Status.Busy = true
+	assert.NoError(t, cl.Status().Update(ctx, instance))
+	runner.Spec.Paused = true
+	_, err := r.handleScaling(ctx, runner)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(currentRunners.WithLabelValues(runner.Namespace, runner.Name)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(desiredRunners.WithLabelValues(runner.Namespace, runner.Name)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(busyRunners.WithLabelValues(runner.Namespace, runner.Name)))
+	assert.Zero(t, testutil.ToFloat64(idleRunners.WithLabelValues(runner.Namespace, runner.Name)))
+}
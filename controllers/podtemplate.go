@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/caitlinelfring/go-env-default"
+	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// the GitHub web URL runners register against, to be changed for GitHub Enterprise Server
+const gitHubURLEnvVarName = "GARO_GITHUB_URL"
+
+const registrationContainerName = "garo-register"
+const registrationVolumeName = "garo-registration"
+const registrationMountPath = "/garo-registration"
+
+// files written by config.sh which run.sh needs to connect as the registered runner
+var runnerConfigFiles = []string{".runner", ".credentials", ".credentials_rsaparams"}
+
+// runnerPodSpec builds the spec of a runner pod from the pod template of the CR merged with its class
+func (r *GithubActionRunnerReconciler) runnerPodSpec(ctx context.Context, instance *garov1alpha1.GithubActionRunner) (corev1.PodSpec, error) {
+	runnerSpec, err := r.runnerSpec(ctx, instance)
+	if err != nil {
+		return corev1.PodSpec{}, err
+	}
+	spec := *runnerSpec.PodTemplateSpec.Spec.DeepCopy()
+	addSharedCache(&spec, instance)
+
+	if instance.Spec.Registration.InjectEnv {
+		runner, err := runnerContainer(&spec, instance)
+		if err != nil {
+			return spec, err
+		}
+		runner.Env = withDefaultEnv(runner.Env, r.runnerEnv(instance))
+	}
+
+	if err := addContainerRuntime(&spec, instance, runnerSpec.ContainerRuntime); err != nil {
+		return spec, err
+	}
+
+	if instance.Spec.Registration.Mode == garov1alpha1.InitContainerRegistration {
+		if err := r.addRegistrationInitContainer(&spec, instance); err != nil {
+			return spec, err
+		}
+	}
+
+	return spec, nil
+}
+
+// runnerContainer returns the container running the runner
+func runnerContainer(spec *corev1.PodSpec, instance *garov1alpha1.GithubActionRunner) (*corev1.Container, error) {
+	name := lo.Ternary(instance.Spec.RunnerContainer != "", instance.Spec.RunnerContainer, "runner")
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == name {
+			return &spec.Containers[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no container named %s in podTemplateSpec", name)
+}
+
+// addRegistrationInitContainer registers the runner in an init container using the image of the runner container.
+// Only the runner configuration written by config.sh is handed to the runner container, the registration token is
+// removed from the environment of all containers so jobs cannot read it.
+func (r *GithubActionRunnerReconciler) addRegistrationInitContainer(spec *corev1.PodSpec, instance *garov1alpha1.GithubActionRunner) error {
+	runner, err := runnerContainer(spec, instance)
+	if err != nil {
+		return err
+	}
+
+	secretName := r.getRegistrationSecretObjectKey(instance).Name
+	for i := range spec.Containers {
+		removeRegistrationToken(&spec.Containers[i], secretName)
+	}
+
+	runnerHome := runnerHome(instance)
+	register := corev1.Container{
+		Name:            registrationContainerName,
+		Image:           runner.Image,
+		ImagePullPolicy: runner.ImagePullPolicy,
+		SecurityContext: runner.SecurityContext.DeepCopy(),
+		Resources:       *runner.Resources.DeepCopy(),
+		WorkingDir:      runnerHome,
+		Command: []string{"/bin/sh", "-c", fmt.Sprintf("./config.sh --unattended --replace && cp %s %s/",
+			strings.Join(runnerConfigFiles, " "), registrationMountPath)},
+		EnvFrom: append([]corev1.EnvFromSource{}, runner.EnvFrom...),
+		Env: withDefaultEnv(append([]corev1.EnvVar{}, runner.Env...), append(runnerInputEnv(instance),
+			corev1.EnvVar{Name: "ACTIONS_RUNNER_INPUT_TOKEN", ValueFrom: registrationTokenRef(secretName)},
+		)),
+		VolumeMounts: []corev1.VolumeMount{{Name: registrationVolumeName, MountPath: registrationMountPath}},
+	}
+	spec.InitContainers = append(spec.InitContainers, register)
+
+	for _, file := range runnerConfigFiles {
+		runner.VolumeMounts = append(runner.VolumeMounts, corev1.VolumeMount{
+			Name:      registrationVolumeName,
+			MountPath: path.Join(runnerHome, file),
+			SubPath:   file,
+		})
+	}
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name:         registrationVolumeName,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+
+	return nil
+}
+
+// runnerHome is the directory the runner is installed in
+func runnerHome(instance *garov1alpha1.GithubActionRunner) string {
+	return lo.Ternary(instance.Spec.Registration.RunnerHome != "", instance.Spec.Registration.RunnerHome, "/home/runner")
+}
+
+// runnerEnv is the environment injected into the runner container, understood by config.sh and the runner images
+// wrapping it
+func (r *GithubActionRunnerReconciler) runnerEnv(instance *garov1alpha1.GithubActionRunner) []corev1.EnvVar {
+	envVars := []corev1.EnvVar{{Name: "GH_ORG", Value: instance.Spec.Organization}}
+	if instance.Spec.Repository != "" {
+		envVars = append(envVars, corev1.EnvVar{Name: "GH_REPO", Value: instance.Spec.Repository})
+	}
+	envVars = append(envVars, runnerInputEnv(instance)...)
+
+	return append(envVars, corev1.EnvVar{
+		Name:      registrationTokenKey,
+		ValueFrom: registrationTokenRef(r.getRegistrationSecretObjectKey(instance).Name),
+	})
+}
+
+// runnerInputEnv holds the arguments of config.sh, which reads them from ACTIONS_RUNNER_INPUT_* variables
+func runnerInputEnv(instance *garov1alpha1.GithubActionRunner) []corev1.EnvVar {
+	registration := instance.Spec.Registration
+	envVars := []corev1.EnvVar{
+		{Name: "ACTIONS_RUNNER_INPUT_URL", Value: runnerURL(instance)},
+		{Name: "ACTIONS_RUNNER_INPUT_NAME", ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+		}},
+	}
+	if len(registration.Labels) > 0 {
+		envVars = append(envVars, corev1.EnvVar{Name: "ACTIONS_RUNNER_INPUT_LABELS", Value: strings.Join(registration.Labels, ",")})
+	}
+	if registration.Group != "" {
+		envVars = append(envVars, corev1.EnvVar{Name: "ACTIONS_RUNNER_INPUT_RUNNERGROUP", Value: registration.Group})
+	}
+	if registration.WorkDir != "" {
+		envVars = append(envVars, corev1.EnvVar{Name: "ACTIONS_RUNNER_INPUT_WORK", Value: registration.WorkDir})
+	}
+
+	return envVars
+}
+
+func registrationTokenRef(secretName string) *corev1.EnvVarSource {
+	return &corev1.EnvVarSource{
+		SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+			Key:                  registrationTokenKey,
+		},
+	}
+}
+
+// withDefaultEnv appends the defaults not already set in envVars
+func withDefaultEnv(envVars []corev1.EnvVar, defaults []corev1.EnvVar) []corev1.EnvVar {
+	for _, envVar := range defaults {
+		_, found := lo.Find(envVars, func(existing corev1.EnvVar) bool {
+			return existing.Name == envVar.Name
+		})
+		if !found {
+			envVars = append(envVars, envVar)
+		}
+	}
+
+	return envVars
+}
+
+// removeRegistrationToken drops the registration token from the environment of the container
+func removeRegistrationToken(container *corev1.Container, secretName string) {
+	container.Env = lo.Reject(container.Env, func(envVar corev1.EnvVar, _ int) bool {
+		return envVar.Name == registrationTokenKey ||
+			(envVar.ValueFrom != nil && envVar.ValueFrom.SecretKeyRef != nil && envVar.ValueFrom.SecretKeyRef.Name == secretName)
+	})
+	container.EnvFrom = lo.Reject(container.EnvFrom, func(envFrom corev1.EnvFromSource, _ int) bool {
+		return envFrom.SecretRef != nil && envFrom.SecretRef.Name == secretName
+	})
+}
+
+// runnerURL is the URL of the organization or repository runners register against
+func runnerURL(instance *garov1alpha1.GithubActionRunner) string {
+	url := strings.TrimSuffix(env.GetDefault(gitHubURLEnvVarName, "https://github.com"), "/") + "/" + instance.Spec.Organization
+	if instance.Spec.Repository != "" {
+		url += "/" + instance.Spec.Repository
+	}
+
+	return url
+}
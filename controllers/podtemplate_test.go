@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testRunner() *v1alpha1.GithubActionRunner {
+	return &v1alpha1.GithubActionRunner{
+		ObjectMeta: metav1.ObjectMeta{Name: "somerunner", Namespace: "someNamespace"},
+		Spec: v1alpha1.GithubActionRunnerSpec{
+			Organization: "someOrg",
+			Repository:   "someRepo",
+			PodTemplateSpec: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:    "runner",
+						Image:   "someImage",
+						Env:     []corev1.EnvVar{{Name: "RUNNER_DEBUG", Value: "true"}, {Name: registrationTokenKey, Value: "someToken"}},
+						EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "somerunner-regtoken"}}}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestRunnerPodSpec(t *testing.T) {
+	r := &GithubActionRunnerReconciler{}
+	instance := testRunner()
+
+	spec, err := r.runnerPodSpec(context.TODO(), instance)
+	assert.NoError(t, err)
+	assert.Equal(t, instance.Spec.PodTemplateSpec.Spec, spec)
+}
+
+func TestRegistrationInitContainer(t *testing.T) {
+	r := &GithubActionRunnerReconciler{}
+	instance := testRunner()
+	instance.Spec.Registration.Mode = v1alpha1.InitContainerRegistration
+
+	spec, err := r.runnerPodSpec(context.TODO(), instance)
+	assert.NoError(t, err)
+
+	// the runner only gets the configuration files
+	runner := spec.Containers[0]
+	assert.Equal(t, []corev1.EnvVar{{Name: "RUNNER_DEBUG", Value: "true"}}, runner.Env)
+	assert.Empty(t, runner.EnvFrom)
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: registrationVolumeName, MountPath: "/home/runner/.runner", SubPath: ".runner"},
+		{Name: registrationVolumeName, MountPath: "/home/runner/.credentials", SubPath: ".credentials"},
+		{Name: registrationVolumeName, MountPath: "/home/runner/.credentials_rsaparams", SubPath: ".credentials_rsaparams"},
+	}, runner.VolumeMounts)
+
+	assert.Len(t, spec.InitContainers, 1)
+	register := spec.InitContainers[0]
+	assert.Equal(t, "someImage", register.Image)
+	assert.Equal(t, "/home/runner", register.WorkingDir)
+	assert.Contains(t, register.Env, corev1.EnvVar{Name: "ACTIONS_RUNNER_INPUT_URL", Value: "https://github.com/someOrg/someRepo"})
+	assert.Contains(t, register.Env, corev1.EnvVar{Name: "RUNNER_DEBUG", Value: "true"})
+	tokenVar, _ := findEnv(register.Env, "ACTIONS_RUNNER_INPUT_TOKEN")
+	assert.Equal(t, "somerunner-regtoken", tokenVar.ValueFrom.SecretKeyRef.Name)
+	assert.Len(t, spec.Volumes, 1)
+
+	// the template is left untouched
+	assert.Len(t, instance.Spec.PodTemplateSpec.Spec.Containers[0].Env, 2)
+
+	instance.Spec.RunnerContainer = "missing"
+	_, err = r.runnerPodSpec(context.TODO(), instance)
+	assert.Error(t, err)
+}
+
+func findEnv(envVars []corev1.EnvVar, name string) (corev1.EnvVar, bool) {
+	for _, envVar := range envVars {
+		if envVar.Name == name {
+			return envVar, true
+		}
+	}
+	return corev1.EnvVar{}, false
+}
+
+func TestInjectRunnerEnv(t *testing.T) {
+	r := &GithubActionRunnerReconciler{}
+	instance := testRunner()
+	instance.Spec.Registration.InjectEnv = true
+	instance.Spec.Registration.Labels = []string{"linux", "docker"}
+	instance.Spec.Registration.Group = "someGroup"
+
+	spec, err := r.runnerPodSpec(context.TODO(), instance)
+	assert.NoError(t, err)
+
+	env := spec.Containers[0].Env
+	assert.Contains(t, env, corev1.EnvVar{Name: "GH_ORG", Value: "someOrg"})
+	assert.Contains(t, env, corev1.EnvVar{Name: "GH_REPO", Value: "someRepo"})
+	assert.Contains(t, env, corev1.EnvVar{Name: "ACTIONS_RUNNER_INPUT_URL", Value: "https://github.com/someOrg/someRepo"})
+	assert.Contains(t, env, corev1.EnvVar{Name: "ACTIONS_RUNNER_INPUT_LABELS", Value: "linux,docker"})
+	assert.Contains(t, env, corev1.EnvVar{Name: "ACTIONS_RUNNER_INPUT_RUNNERGROUP", Value: "someGroup"})
+	_, found := findEnv(env, "ACTIONS_RUNNER_INPUT_WORK")
+	assert.False(t, found)
+	nameVar, _ := findEnv(env, "ACTIONS_RUNNER_INPUT_NAME")
+	assert.Equal(t, "metadata.name", nameVar.ValueFrom.FieldRef.FieldPath)
+
+	// variables set in the template win
+	tokenVar, _ := findEnv(env, registrationTokenKey)
+	assert.Equal(t, "someToken", tokenVar.Value)
+	assert.Len(t, lo.Filter(env, func(envVar corev1.EnvVar, _ int) bool { return envVar.Name == registrationTokenKey }), 1)
+
+	// in InitContainer mode the injected variables configure the registration but the token is still removed
+	instance.Spec.Registration.Mode = v1alpha1.InitContainerRegistration
+	spec, err = r.runnerPodSpec(context.TODO(), instance)
+	assert.NoError(t, err)
+	_, found = findEnv(spec.Containers[0].Env, registrationTokenKey)
+	assert.False(t, found)
+	assert.Contains(t, spec.InitContainers[0].Env, corev1.EnvVar{Name: "ACTIONS_RUNNER_INPUT_LABELS", Value: "linux,docker"})
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"github.com/samber/lo"
 	v1 "k8s.io/api/core/v1"
 	"strings"
 )
@@ -9,6 +10,21 @@ func isEvicted(pod *v1.Pod) bool {
 	return strings.Contains(pod.Status.Reason, "Evicted")
 }
 
+// isCompleted returns whether the runner of the pod is done. The container runtime sidecar keeps running after the
+// runner exited, so a pod with one never succeeds, it is completed once its other containers terminated successfully.
 func isCompleted(pod *v1.Pod) bool {
-	return pod.Status.Phase == v1.PodSucceeded
+	if pod.Status.Phase == v1.PodSucceeded {
+		return true
+	}
+
+	runners := lo.Reject(pod.Status.ContainerStatuses, func(status v1.ContainerStatus, _ int) bool {
+		return status.Name == runtimeContainerName
+	})
+	if pod.Status.Phase != v1.PodRunning || len(runners) == len(pod.Status.ContainerStatuses) {
+		return false
+	}
+
+	return len(runners) > 0 && lo.EveryBy(runners, func(status v1.ContainerStatus) bool {
+		return status.State.Terminated != nil && status.State.Terminated.ExitCode == 0
+	})
 }
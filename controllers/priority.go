@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// lowerPriorityPools returns the other pools in the namespace of the instance with a lower priority, lowest first.
+// Paused pools are left alone.
+func (r *GithubActionRunnerReconciler) lowerPriorityPools(ctx context.Context, instance *garov1alpha1.GithubActionRunner) ([]garov1alpha1.GithubActionRunner, error) {
+	runnerList := &garov1alpha1.GithubActionRunnerList{}
+	if err := r.GetClient().List(ctx, runnerList, client.InNamespace(instance.Namespace)); err != nil {
+		return nil, err
+	}
+
+	pools := lo.Filter(runnerList.Items, func(pool garov1alpha1.GithubActionRunner, _ int) bool {
+		return pool.Spec.Priority < instance.Spec.Priority && pool.DeletionTimestamp.IsZero() && !pool.Spec.Paused
+	})
+	sort.SliceStable(pools, func(i, j int) bool {
+		return pools[i].Spec.Priority < pools[j].Spec.Priority
+	})
+
+	return pools, nil
+}
+
+// throttledHigherPriorityPool returns the name of a pool in the namespace with a higher priority than the instance
+// which is throttled by a quota and is still scaling, if any. Lower priority pools leave the room of the quota to it.
+func (r *GithubActionRunnerReconciler) throttledHigherPriorityPool(ctx context.Context, instance *garov1alpha1.GithubActionRunner) (string, error) {
+	runnerList := &garov1alpha1.GithubActionRunnerList{}
+	if err := r.GetClient().List(ctx, runnerList, client.InNamespace(instance.Namespace)); err != nil {
+		return "", err
+	}
+
+	pool, found := lo.Find(runnerList.Items, func(pool garov1alpha1.GithubActionRunner) bool {
+		return pool.Spec.Priority > instance.Spec.Priority && pool.DeletionTimestamp.IsZero() &&
+			!pool.Spec.Paused && !pool.Spec.Drain && meta.IsStatusConditionTrue(pool.Status.Conditions, quotaThrottledCondition)
+	})
+	if !found {
+		return "", nil
+	}
+
+	return pool.Name, nil
+}
+
+// reclaimForUnschedulablePods reclaims idle runners of lower priority pools for the pods of the instance the scheduler
+// found no node for, reflected in the Unschedulable condition. The pods are scheduled once the nodes have room.
+func (r *GithubActionRunnerReconciler) reclaimForUnschedulablePods(ctx context.Context, instance *garov1alpha1.GithubActionRunner, podRunnerPairs podRunnerPairList) error {
+	unschedulable := lo.CountBy(podRunnerPairs.pairs, func(pair podRunnerPair) bool {
+		return isUnschedulable(&pair.pod)
+	})
+	if unschedulable == 0 {
+		setCondition(instance, unschedulableCondition, false, "Scheduled", "")
+		return nil
+	}
+
+	setCondition(instance, unschedulableCondition, true, "NoNodeAvailable", fmt.Sprintf("%d runner pods cannot be scheduled", unschedulable))
+	_, err := r.reclaimIdleRunners(ctx, instance, unschedulable)
+	return err
+}
+
+// isUnschedulable tells if the scheduler found no node with room for the pod
+func isUnschedulable(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodPending && pod.DeletionTimestamp.IsZero() &&
+		lo.ContainsBy(pod.Status.Conditions, func(condition corev1.PodCondition) bool {
+			return condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse &&
+				condition.Reason == corev1.PodReasonUnschedulable
+		})
+}
+
+// reclaimIdleRunners removes up to amount idle runners of lower priority pools in the namespace like their regular
+// scale down, making room for runners of the instance a quota or the capacity of the cluster did not allow. It returns
+// how many were removed, the room is taken by the instance once their pods are gone.
+func (r *GithubActionRunnerReconciler) reclaimIdleRunners(ctx context.Context, instance *garov1alpha1.GithubActionRunner, amount int) (int, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+	pools, err := r.lowerPriorityPools(ctx, instance)
+	if err != nil || len(pools) == 0 {
+		return 0, err
+	}
+
+	// pods being deleted still count against quotas, wait for them instead of reclaiming more
+	podList := &corev1.PodList{}
+	if err := r.GetClient().List(ctx, podList, client.InNamespace(instance.Namespace), client.HasLabels{poolLabel}); err != nil {
+		return 0, err
+	}
+	if lo.ContainsBy(podList.Items, func(pod corev1.Pod) bool { return !pod.DeletionTimestamp.IsZero() }) {
+		logger.Info("Waiting for runner pods being deleted before reclaiming idle runners")
+		return 0, nil
+	}
+
+	reclaimed := 0
+	for i := 0; i < len(pools) && reclaimed < amount; i++ {
+		pool := &pools[i]
+		podRunnerPairs, err := r.getPodRunnerPairs(ctx, pool)
+		if err != nil {
+			logger.Error(err, "Skipping pool when reclaiming idle runners", "pool", pool.Name)
+			continue
+		}
+		if !podRunnerPairs.inSync() {
+			continue
+		}
+
+		// the idle runners are picked once, the status of the pool is left to its own reconcile
+		reason := scaleDownReason(podRunnerPairs, pool)
+		for _, pair := range podRunnerPairs.getIdles(pool.Spec.DeletionOrder, pool.Spec.MinTTL.Duration) {
+			if reclaimed == amount {
+				break
+			}
+			removed, err := r.removeIdleRunner(ctx, pool, pair, reason)
+			if err != nil {
+				return reclaimed, err
+			}
+			if !removed {
+				continue
+			}
+			reclaimed++
+			r.GetRecorder().Event(pool, corev1.EventTypeNormal, "Preempted", fmt.Sprintf("Idle runner reclaimed by pool %s of priority %d", instance.Name, instance.Spec.Priority))
+		}
+	}
+
+	if reclaimed > 0 {
+		logger.Info("Reclaimed idle runners of lower priority pools", "reclaimed", reclaimed)
+		r.GetRecorder().Event(instance, corev1.EventTypeNormal, "Preempting", fmt.Sprintf("Reclaimed %d idle runners of lower priority pools", reclaimed))
+	}
+
+	return reclaimed, nil
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/google/go-github/v59/github"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestReclaimIdleRunners(t *testing.T) {
+	ctx := context.TODO()
+	low := testRunner()
+	low.Name = "low"
+	low.Spec.Organization = "lowOrg"
+	low.UID = "lowUID"
+	low.TypeMeta = metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "GithubActionRunner"}
+	high := testRunner()
+	high.Name = "high"
+	high.Spec.Priority = 10
+	high.UID = "highUID"
+	high.TypeMeta = low.TypeMeta
+	quota := &v1alpha1.GithubActionRunnerQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: low.Namespace},
+		Spec:       v1alpha1.GithubActionRunnerQuotaSpec{MaxRunners: lo.ToPtr(2)},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerList{}, &v1alpha1.GithubActionRunnerInstance{}, &v1alpha1.GithubActionRunnerQuota{}, &v1alpha1.GithubActionRunnerQuotaList{})
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(low, high, quota).WithStatusSubresource(&v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerInstance{}).Build()
+	api := new(mockAPI)
+	r := &GithubActionRunnerReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, record.NewFakeRecorder(20), nil), Log: zap.New(), GithubAPI: api}
+
+	// the low priority pool fills the quota with idle runners
+	assert.Equal(t, 2, lo.Must(r.scaleUp(ctx, 2, low)))
+	lowPods := lo.Must(r.listRelatedPods(ctx, low))
+	runners := lo.Map(lowPods.Items, func(pod corev1.Pod, i int) *github.Runner {
+		return &github.Runner{ID: github.Int64(int64(i + 1)), Name: github.String(pod.Name), Status: github.String("online"), Busy: github.Bool(false)}
+	})
+	api.On("GetRunners", "lowOrg", "someRepo", mock.Anything).Return(runners, nil)
+
+	assert.Zero(t, lo.Must(r.scaleUp(ctx, 1, high)))
+	assert.True(t, meta.IsStatusConditionTrue(high.Status.Conditions, quotaThrottledCondition))
+	assert.NoError(t, cl.Status().Update(ctx, high))
+
+	stored := &v1alpha1.GithubActionRunner{}
+	assert.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(low), stored))
+	assert.Equal(t, 1, lo.Must(r.reclaimIdleRunners(ctx, high, 1)))
+	assert.Len(t, lo.Must(r.listRelatedPods(ctx, low)).Items, 1)
+	// the runners of the pool are listed once, and its status is left to its own reconcile
+	api.AssertNumberOfCalls(t, "GetRunners", 1)
+	resourceVersion := stored.ResourceVersion
+	assert.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(low), stored))
+	assert.Equal(t, resourceVersion, stored.ResourceVersion)
+
+	// the low priority pool leaves the room to the throttled pool
+	created, err := r.scaleUp(ctx, 1, low)
+	assert.NoError(t, err)
+	assert.Zero(t, created)
+	assert.Contains(t, meta.FindStatusCondition(low.Status.Conditions, quotaThrottledCondition).Message, "pool high of a higher priority is throttled")
+
+	assert.Equal(t, 1, lo.Must(r.scaleUp(ctx, 1, high)))
+
+	// pools of the same or a higher priority are never reclaimed from
+	assert.Zero(t, lo.Must(r.reclaimIdleRunners(ctx, low, 1)))
+
+	// the low priority pool still scales up to its minimum size while the pool of a higher priority is throttled
+	quota.Spec.MaxRunners = lo.ToPtr(4)
+	assert.NoError(t, cl.Update(ctx, quota))
+	low.Spec.MinRunners = 2
+	assert.Equal(t, 1, lo.Must(r.scaleUp(ctx, 2, low)))
+}
+
+func TestReclaimForUnschedulablePods(t *testing.T) {
+	ctx := context.TODO()
+	low := testRunner()
+	low.Name = "low"
+	low.Spec.Organization = "lowOrg"
+	low.UID = "lowUID"
+	low.TypeMeta = metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "GithubActionRunner"}
+	high := testRunner()
+	high.Name = "high"
+	high.Spec.Priority = 10
+	high.UID = "highUID"
+	high.TypeMeta = low.TypeMeta
+
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerList{}, &v1alpha1.GithubActionRunnerInstance{}, &v1alpha1.GithubActionRunnerQuota{}, &v1alpha1.GithubActionRunnerQuotaList{})
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(low, high).WithStatusSubresource(&v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerInstance{}).Build()
+	api := new(mockAPI)
+	r := &GithubActionRunnerReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, record.NewFakeRecorder(20), nil), Log: zap.New(), GithubAPI: api}
+
+	// the low priority pool takes the room of the cluster with idle runners, there is no quota
+	assert.Equal(t, 2, lo.Must(r.scaleUp(ctx, 2, low)))
+	lowPods := lo.Must(r.listRelatedPods(ctx, low))
+	runners := lo.Map(lowPods.Items, func(pod corev1.Pod, i int) *github.Runner {
+		return &github.Runner{ID: github.Int64(int64(i + 1)), Name: github.String(pod.Name), Status: github.String("online"), Busy: github.Bool(false)}
+	})
+	api.On("GetRunners", "lowOrg", "someRepo", mock.Anything).Return(runners, nil)
+
+	assert.Equal(t, 1, lo.Must(r.scaleUp(ctx, 1, high)))
+	highPods := lo.Must(r.listRelatedPods(ctx, high))
+
+	// a pod waiting for its image is not reclaimed for
+	highPods.Items[0].Status = corev1.PodStatus{Phase: corev1.PodPending, Conditions: []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionTrue}}}
+	assert.NoError(t, r.reclaimForUnschedulablePods(ctx, high, from(highPods, nil)))
+	assert.False(t, meta.IsStatusConditionTrue(high.Status.Conditions, unschedulableCondition))
+	assert.Len(t, lo.Must(r.listRelatedPods(ctx, low)).Items, 2)
+
+	highPods.Items[0].Status.Conditions = []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable}}
+	assert.NoError(t, r.reclaimForUnschedulablePods(ctx, high, from(highPods, nil)))
+	assert.True(t, meta.IsStatusConditionTrue(high.Status.Conditions, unschedulableCondition))
+	assert.Len(t, lo.Must(r.listRelatedPods(ctx, low)).Items, 1)
+	assert.NoError(t, cl.Status().Update(ctx, high))
+
+	// without a quota shared with the pool of unschedulable pods the low priority pool is not held back
+	assert.Equal(t, 1, lo.Must(r.scaleUp(ctx, 1, low)))
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// quotaAllowance returns how many of amount pods with the spec fit into the quotas of the namespace and are not held
+// back for a pool of a higher priority throttled by them, and if less than amount what limits them
+func (r *GithubActionRunnerReconciler) quotaAllowance(ctx context.Context, instance *garov1alpha1.GithubActionRunner, amount int, spec *corev1.PodSpec) (int, string, error) {
+	quotaList := &garov1alpha1.GithubActionRunnerQuotaList{}
+	if err := r.GetClient().List(ctx, quotaList, client.InNamespace(instance.Namespace)); err != nil {
+		return 0, "", err
+	}
+	// runner pods of all pools in the namespace
+	podList := &corev1.PodList{}
+	if err := r.GetClient().List(ctx, podList, client.InNamespace(instance.Namespace), client.HasLabels{poolLabel}); err != nil {
+		return 0, "", err
+	}
+	pods := lo.Filter(podList.Items, func(pod corev1.Pod, _ int) bool {
+		return pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed
+	})
+	used := corev1.ResourceList{}
+	for i := range pods {
+		addResources(used, podRequests(&pods[i].Spec))
+	}
+	perPod := podRequests(spec)
+
+	allowed, limitedBy := amount, ""
+	for _, quota := range quotaList.Items {
+		if quota.Spec.MaxRunners != nil {
+			if fit := *quota.Spec.MaxRunners - len(pods); fit < allowed {
+				allowed = fit
+				limitedBy = fmt.Sprintf("quota %s allows %d runners and %d exist", quota.Name, *quota.Spec.MaxRunners, len(pods))
+			}
+		}
+		for name, limit := range quota.Spec.Requests {
+			request := perPod[name]
+			if request.IsZero() {
+				continue
+			}
+			usedByName := used[name]
+			remaining := limit.DeepCopy()
+			remaining.Sub(usedByName)
+			if fit := int(remaining.MilliValue() / request.MilliValue()); fit < allowed {
+				allowed = fit
+				limitedBy = fmt.Sprintf("quota %s allows %s %s and %s is requested", quota.Name, limit.String(), name, usedByName.String())
+			}
+		}
+	}
+
+	// the quotas of the namespace are shared by all its pools, the room left is held back for a throttled pool of a
+	// higher priority, except for scaling up to the minimum size of the pool
+	if allowed > 0 && len(quotaList.Items) > 0 {
+		higherPriorityPool, err := r.throttledHigherPriorityPool(ctx, instance)
+		if err != nil {
+			return 0, "", err
+		}
+		if higherPriorityPool != "" {
+			own := lo.CountBy(pods, func(pod corev1.Pod) bool { return pod.Labels[poolLabel] == instance.Name })
+			if belowMin := lo.Max([]int{instance.Spec.MinRunners - own, 0}); belowMin < allowed {
+				allowed = belowMin
+				limitedBy = fmt.Sprintf("pool %s of a higher priority is throttled", higherPriorityPool)
+			}
+		}
+	}
+
+	return lo.Max([]int{allowed, 0}), limitedBy, nil
+}
+
+// podRequests returns the resources the scheduler reserves for a pod, containers without requests count with their
+// limits like the API server defaults them
+func podRequests(spec *corev1.PodSpec) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for _, container := range spec.Containers {
+		addResources(requests, containerRequests(container))
+	}
+	// init containers run one at a time before the containers
+	for _, container := range spec.InitContainers {
+		for name, quantity := range containerRequests(container) {
+			if current, found := requests[name]; !found || quantity.Cmp(current) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	addResources(requests, spec.Overhead)
+
+	return requests
+}
+
+func containerRequests(container corev1.Container) corev1.ResourceList {
+	return lo.Assign(container.Resources.Limits, container.Resources.Requests)
+}
+
+func addResources(total corev1.ResourceList, add corev1.ResourceList) {
+	for name, quantity := range add {
+		sum := total[name]
+		if sum.Format == "" {
+			sum = resource.Quantity{Format: quantity.Format}
+		}
+		sum.Add(quantity)
+		total[name] = sum
+	}
+}
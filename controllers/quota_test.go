@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func withRequests(cpu string, memory string) corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{Requests: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+	}}
+}
+
+func TestPodRequests(t *testing.T) {
+	spec := &corev1.PodSpec{
+		InitContainers: []corev1.Container{{Resources: withRequests("2", "64Mi")}},
+		Containers: []corev1.Container{
+			{Resources: withRequests("500m", "1Gi")},
+			{Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}}},
+		},
+	}
+
+	requests := podRequests(spec)
+	assert.True(t, resource.MustParse("2").Equal(requests[corev1.ResourceCPU]), "the init container requests more cpu")
+	assert.True(t, resource.MustParse("1Gi").Equal(requests[corev1.ResourceMemory]))
+}
+
+func TestQuotaThrottlesScaleUp(t *testing.T) {
+	ctx := context.TODO()
+	instance := testRunner()
+	instance.UID = "someUID"
+	instance.Kind = "GithubActionRunner"
+	instance.Spec.PodTemplateSpec.Spec.Containers[0].Resources = withRequests("1", "1Gi")
+
+	// a runner of another pool in the namespace counts against the quota
+	otherPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-pod", Namespace: instance.Namespace, Labels: map[string]string{poolLabel: "other"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "runner", Resources: withRequests("2", "1Gi")}}},
+	}
+	quota := &v1alpha1.GithubActionRunnerQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: instance.Namespace},
+		Spec: v1alpha1.GithubActionRunnerQuotaSpec{
+			MaxRunners: lo.ToPtr(10),
+			Requests:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4500m")},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerInstance{}, &v1alpha1.GithubActionRunnerQuota{}, &v1alpha1.GithubActionRunnerQuotaList{})
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(instance, otherPod, quota).WithStatusSubresource(&v1alpha1.GithubActionRunnerInstance{}).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &GithubActionRunnerReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, recorder, nil), Log: zap.New()}
+
+	assert.Equal(t, 1, lo.Must(r.scaleUp(ctx, 1, instance)))
+	assert.True(t, meta.IsStatusConditionFalse(instance.Status.Conditions, quotaThrottledCondition))
+
+	created, err := r.scaleUp(ctx, 3, instance)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, created, "2 of 4.5 cpus are left")
+	condition := meta.FindStatusCondition(instance.Status.Conditions, quotaThrottledCondition)
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	assert.Equal(t, "Creating 1 of 3 runners, quota team allows 4500m cpu and 3 is requested", condition.Message)
+
+	quota.Spec.MaxRunners = lo.ToPtr(3)
+	assert.NoError(t, cl.Update(ctx, quota))
+	created, err = r.scaleUp(ctx, 1, instance)
+	assert.NoError(t, err)
+	assert.Zero(t, created)
+	assert.Contains(t, meta.FindStatusCondition(instance.Status.Conditions, quotaThrottledCondition).Message, "quota team allows 3 runners and 3 exist")
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/caitlinelfring/go-env-default"
+	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/evryfs/github-actions-runner-operator/controllers/githubapi"
+	"github.com/go-logr/logr"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// percentage of the GitHub API quota held back, reconciliation is paused until the window resets when reached
+const rateLimitReserveEnvVarName = "GARO_RATELIMIT_RESERVE_PERCENT"
+
+// rateLimitBackoff returns how long to hold off calls to GitHub for the pool if the remaining quota of its credential is low
+func (r *GithubActionRunnerReconciler) rateLimitBackoff(ctx context.Context, instance *garov1alpha1.GithubActionRunner) (time.Duration, bool, error) {
+	credentials, err := r.credentialsForRef(ctx, instance)
+	if err != nil {
+		return 0, false, err
+	}
+
+	rate, found := r.GithubAPI.RateLimit(instance.Spec.Organization, credentials)
+	if !found {
+		return 0, false, nil
+	}
+
+	reserve := rate.Limit * env.GetIntDefault(rateLimitReserveEnvVarName, 10) / 100
+	if rate.Remaining > reserve {
+		return 0, false, nil
+	}
+
+	return time.Until(rate.Reset.Time), true, nil
+}
+
+// manageRateLimited requeues the CR once the quota is expected to be available again, instead of failing the reconcile
+func (r *GithubActionRunnerReconciler) manageRateLimited(ctx context.Context, instance *garov1alpha1.GithubActionRunner, retryAfter time.Duration, message string) (reconcile.Result, error) {
+	// a window reset in the past still needs a requeue
+	retryAfter = lo.Max([]time.Duration{retryAfter, time.Second})
+	logr.FromContextOrDiscard(ctx).Info("GitHub API rate limited, backing off", "retryAfter", retryAfter)
+	setCondition(instance, rateLimitedCondition, true, "QuotaExhausted", message)
+	r.recordLastObservedPoolState(ctx, instance)
+	return r.ManageOutcomeWithRequeue(ctx, instance, nil, retryAfter)
+}
+
+// rateLimitedError returns the rate limit error wrapped in err, if any
+func rateLimitedError(err error) (*githubapi.RateLimitedError, bool) {
+	var rateLimited *githubapi.RateLimitedError
+	return rateLimited, errors.As(err, &rateLimited)
+}
+
+func lowQuotaMessage(retryAfter time.Duration) string {
+	return fmt.Sprintf("GitHub API quota low, pausing until the rate limit window resets in %s", retryAfter.Round(time.Second))
+}
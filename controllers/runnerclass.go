@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+
+	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// runnerClassIndex indexes GithubActionRunners by the name of the ClusterGithubActionRunnerClass they reference
+const runnerClassIndex = "spec.className"
+
+func indexRunnerClass(obj client.Object) []string {
+	runner := obj.(*garov1alpha1.GithubActionRunner)
+	if runner.Spec.ClassName == "" {
+		return nil
+	}
+	return []string{runner.Spec.ClassName}
+}
+
+// classCredentialsSecretIndex indexes ClusterGithubActionRunnerClasses by the name of the secret holding their credentials
+const classCredentialsSecretIndex = "spec.credentialsSecret"
+
+func indexClassCredentialsSecret(obj client.Object) []string {
+	class := obj.(*garov1alpha1.ClusterGithubActionRunnerClass)
+	if class.Spec.CredentialsSource != "" && class.Spec.CredentialsSource != garov1alpha1.SecretSource {
+		return nil
+	}
+	if class.Spec.TokenRef != nil && class.Spec.TokenRef.Name != "" {
+		return []string{class.Spec.TokenRef.Name}
+	}
+	if class.Spec.AppSecretRef != nil && class.Spec.AppSecretRef.Name != "" {
+		return []string{class.Spec.AppSecretRef.Name}
+	}
+	return nil
+}
+
+// mergedSpecsKey is the context key of the specs merged with their class during a reconcile
+type mergedSpecsKey struct{}
+
+// withMergedSpecs returns a context in which the class of a CR is only merged once per reconcile
+func withMergedSpecs(ctx context.Context) context.Context {
+	return context.WithValue(ctx, mergedSpecsKey{}, map[types.NamespacedName]*garov1alpha1.GithubActionRunnerSpec{})
+}
+
+// runnerSpec returns the spec of the CR merged with the class it references. The merged spec is kept apart from the
+// CR, the controller never updates the spec of the CR, and a status update resets the CR to the stored spec.
+func (r *GithubActionRunnerReconciler) runnerSpec(ctx context.Context, instance *garov1alpha1.GithubActionRunner) (*garov1alpha1.GithubActionRunnerSpec, error) {
+	merged, _ := ctx.Value(mergedSpecsKey{}).(map[types.NamespacedName]*garov1alpha1.GithubActionRunnerSpec)
+	if spec, found := merged[client.ObjectKeyFromObject(instance)]; found {
+		return spec, nil
+	}
+
+	spec := instance.Spec.DeepCopy()
+	if spec.ClassName != "" {
+		class := &garov1alpha1.ClusterGithubActionRunnerClass{}
+		if err := r.GetClient().Get(ctx, client.ObjectKey{Name: spec.ClassName}, class); err != nil {
+			return nil, err
+		}
+		if err := spec.ApplyClass(&class.Spec); err != nil {
+			return nil, err
+		}
+	}
+
+	if merged != nil {
+		merged[client.ObjectKeyFromObject(instance)] = spec
+	}
+	return spec, nil
+}
+
+// runnerClassHandler enqueues the GithubActionRunners in all namespaces referencing a class when it changes
+func (r *GithubActionRunnerReconciler) runnerClassHandler() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, class client.Object) []reconcile.Request {
+		runnerList := &garov1alpha1.GithubActionRunnerList{}
+		if err := r.GetClient().List(ctx, runnerList, client.MatchingFields{runnerClassIndex: class.GetName()}); err != nil {
+			r.Log.Error(err, "Failed to list runners referencing class", "class", class.GetName())
+			return nil
+		}
+
+		requests := make([]reconcile.Request, 0, len(runnerList.Items))
+		for i := range runnerList.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&runnerList.Items[i])})
+		}
+		return requests
+	})
+}
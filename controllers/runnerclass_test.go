@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestRunnerSpec(t *testing.T) {
+	ctx := context.TODO()
+	class := &v1alpha1.ClusterGithubActionRunnerClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "someClass"},
+		Spec: v1alpha1.ClusterGithubActionRunnerClassSpec{
+			PodTemplateSpec: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "platform"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "runner", Image: "classImage"}},
+				},
+			},
+			ContainerRuntime: v1alpha1.DindRuntime,
+		},
+	}
+	instance := testRunner()
+	instance.Spec.ClassName = "someClass"
+	instance.Spec.PodTemplateSpec.Spec.Containers[0].Image = ""
+
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{}, &v1alpha1.ClusterGithubActionRunnerClass{})
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(class).Build()
+	r := &GithubActionRunnerReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, record.NewFakeRecorder(10), nil), Log: zap.New()}
+
+	merged, err := r.runnerSpec(ctx, instance)
+	assert.NoError(t, err)
+	assert.Equal(t, "platform", merged.PodTemplateSpec.Labels["team"])
+	assert.Empty(t, instance.Spec.PodTemplateSpec.Labels["team"], "the spec of the CR is left as stored")
+	spec, err := r.runnerPodSpec(ctx, instance)
+	assert.NoError(t, err)
+	runner := spec.Containers[0]
+	assert.Equal(t, "classImage", runner.Image)
+	_, found := findEnv(runner.Env, "RUNNER_DEBUG")
+	assert.True(t, found, "the environment of the CR is kept")
+	assert.Len(t, spec.Containers, 2, "the container runtime of the class is added")
+
+	instance.Spec.ClassName = "otherClass"
+	_, err = r.runnerSpec(ctx, instance)
+	assert.True(t, apierrors.IsNotFound(err))
+}
@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"context"
+
+	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v59/github"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// poolRecord returns what the runner instances of the pool record of it, to unregister their runner once it is gone
+func (r *GithubActionRunnerReconciler) poolRecord(ctx context.Context, cr *garov1alpha1.GithubActionRunner) (*garov1alpha1.PoolRecord, error) {
+	spec, err := r.runnerSpec(ctx, cr)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &garov1alpha1.PoolRecord{
+		UID:               cr.UID,
+		Organization:      spec.Organization,
+		Repository:        spec.Repository,
+		CredentialsSource: spec.CredentialsSource,
+		AppSecretRef:      spec.AppSecretRef.DeepCopy(),
+		InstallationID:    spec.InstallationID,
+	}
+	if spec.TokenRef.Name != "" {
+		record.TokenRef = spec.TokenRef.DeepCopy()
+	}
+	return record, nil
+}
+
+// createRunnerInstance creates the GithubActionRunnerInstance recording the runner hosted by pod and its pool.
+// The instance is owned by the pod so it is garbage collected along with it.
+func (r *GithubActionRunnerReconciler) createRunnerInstance(ctx context.Context, cr *garov1alpha1.GithubActionRunner, pod *corev1.Pod) error {
+	record, err := r.poolRecord(ctx, cr)
+	if err != nil {
+		return err
+	}
+	runnerInstance := &garov1alpha1.GithubActionRunnerInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Labels:    map[string]string{poolLabel: cr.Name},
+		},
+		Spec: garov1alpha1.GithubActionRunnerInstanceSpec{
+			Pool:    cr.Name,
+			PodName: pod.Name,
+		},
+	}
+	if err := controllerutil.SetControllerReference(pod, runnerInstance, r.GetScheme()); err != nil {
+		return err
+	}
+
+	if err := r.GetClient().Create(ctx, runnerInstance); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+
+	runnerInstance.Status = runnerInstanceStatus(runnerInstance.Status, podRunnerPair{pod: *pod})
+	runnerInstance.Status.Pool = record
+	return r.GetClient().Status().Update(ctx, runnerInstance)
+}
+
+// updateRunnerInstances keeps the GithubActionRunnerInstance records of the pool in line with the observed pods and runners
+func (r *GithubActionRunnerReconciler) updateRunnerInstances(ctx context.Context, cr *garov1alpha1.GithubActionRunner, podRunnerPairs podRunnerPairList) error {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	instanceList := &garov1alpha1.GithubActionRunnerInstanceList{}
+	if err := r.GetClient().List(ctx, instanceList, client.InNamespace(cr.Namespace), client.MatchingLabels{poolLabel: cr.Name}); err != nil {
+		return err
+	}
+	instancesByName := lo.SliceToMap(instanceList.Items, func(item garov1alpha1.GithubActionRunnerInstance) (string, garov1alpha1.GithubActionRunnerInstance) {
+		return item.Name, item
+	})
+	record, err := r.poolRecord(ctx, cr)
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range podRunnerPairs.pairs {
+		runnerInstance, found := instancesByName[pair.pod.Name]
+		delete(instancesByName, pair.pod.Name)
+		if !found {
+			// pods created before instances were introduced, or the instance was removed by hand
+			if util.IsBeingDeleted(&pair.pod) {
+				continue
+			}
+			if err := r.createRunnerInstance(ctx, cr, &pair.pod); err != nil {
+				return err
+			}
+			continue
+		}
+
+		status := runnerInstanceStatus(runnerInstance.Status, pair)
+		status.Pool = record
+		if equality.Semantic.DeepEqual(status, runnerInstance.Status) {
+			continue
+		}
+		if runnerInstance.Status.RegisteredAt == nil && status.RegisteredAt != nil && status.PodCreatedAt != nil {
+			registrationLatency.WithLabelValues(cr.Namespace, cr.Name).Observe(status.RegisteredAt.Sub(status.PodCreatedAt.Time).Seconds())
+		}
+		runnerInstance.Status = status
+		if err := r.GetClient().Status().Update(ctx, &runnerInstance); err != nil {
+			return err
+		}
+	}
+
+	// instances left have lost their pod
+	for _, orphan := range instancesByName {
+		logger.Info("Deleting runner instance without pod", "name", orphan.Name)
+		orphan := orphan
+		if err := r.DeleteResourceIfExists(ctx, &orphan); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordLastObservedPoolState updates the pool gauges when the runners are not fetched from GitHub, like while paused
+// or rate limited. The state of the runners is taken from their instance records, as last observed.
+func (r *GithubActionRunnerReconciler) recordLastObservedPoolState(ctx context.Context, cr *garov1alpha1.GithubActionRunner) {
+	podList, err := r.listRelatedPods(ctx, cr)
+	if err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "Failed to record pool state")
+		return
+	}
+	instanceList := &garov1alpha1.GithubActionRunnerInstanceList{}
+	if err := r.GetClient().List(ctx, instanceList, client.InNamespace(cr.Namespace), client.MatchingLabels{poolLabel: cr.Name}); err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "Failed to record pool state")
+		return
+	}
+
+	registered := lo.Filter(instanceList.Items, func(item garov1alpha1.GithubActionRunnerInstance, _ int) bool {
+		return item.Status.RunnerID != 0
+	})
+	runners := lo.Map(registered, func(item garov1alpha1.GithubActionRunnerInstance, _ int) *github.Runner {
+		return &github.Runner{ID: github.Int64(item.Status.RunnerID), Name: github.String(item.Status.RunnerName), Busy: github.Bool(item.Status.Busy)}
+	})
+	podRunnerPairs := from(podList, runners)
+	recordPoolState(client.ObjectKeyFromObject(cr), podRunnerPairs, podRunnerPairs.numPods())
+}
+
+// runnerInstanceStatus computes the status of a runner instance from the previous status and the current pod and runner
+func runnerInstanceStatus(previous garov1alpha1.GithubActionRunnerInstanceStatus, pair podRunnerPair) garov1alpha1.GithubActionRunnerInstanceStatus {
+	status := *previous.DeepCopy()
+	status.Phase = runnerPhase(pair)
+	status.RunnerID = pair.runner.GetID()
+	status.RunnerName = pair.runner.GetName()
+	status.Labels = lo.Map(pair.runner.Labels, func(label *github.RunnerLabels, _ int) string {
+		return label.GetName()
+	})
+	if len(status.Labels) == 0 {
+		status.Labels = nil
+	}
+	status.Online = pair.runner.GetStatus() == "online"
+	status.Busy = pair.runner.GetBusy()
+
+	if status.PodCreatedAt == nil && !pair.pod.CreationTimestamp.IsZero() {
+		status.PodCreatedAt = pair.pod.CreationTimestamp.DeepCopy()
+	}
+	if status.RegisteredAt == nil && status.RunnerID != 0 {
+		now := metav1.Now()
+		status.RegisteredAt = &now
+	}
+	if status.Busy && status.BusySince == nil {
+		now := metav1.Now()
+		status.BusySince = &now
+	} else if !status.Busy && previous.Busy {
+		// job finished, the job URLs are set again by the workflow_job webhook for the next one
+		status.BusySince = nil
+		status.JobURL = ""
+		status.RunURL = ""
+	}
+	if status.TerminatingAt == nil && pair.pod.DeletionTimestamp != nil {
+		status.TerminatingAt = pair.pod.DeletionTimestamp.DeepCopy()
+	}
+
+	return status
+}
+
+func runnerPhase(pair podRunnerPair) garov1alpha1.RunnerPhase {
+	switch {
+	case util.IsBeingDeleted(&pair.pod):
+		return garov1alpha1.RunnerTerminating
+	case pair.runner.GetID() == 0:
+		return garov1alpha1.RunnerPending
+	case pair.runner.GetBusy():
+		return garov1alpha1.RunnerBusy
+	case pair.runner.GetStatus() != "online":
+		return garov1alpha1.RunnerOffline
+	default:
+		return garov1alpha1.RunnerIdle
+	}
+}
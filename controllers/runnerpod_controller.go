@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/evryfs/github-actions-runner-operator/controllers/credentialprovider"
+	"github.com/evryfs/github-actions-runner-operator/controllers/githubapi"
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v59/github"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// retry period of runners failing to unregister
+const unregisterRetryPeriod = time.Minute
+
+// recordedPool returns the pool of the pod as recorded in the status of its runner instance, which only the operator
+// writes. Instances not controlled by the pod or recording another pool than the owner of the pod are not trusted.
+func (r *RunnerPodReconciler) recordedPool(ctx context.Context, pod *corev1.Pod, owner *metav1.OwnerReference) (*garov1alpha1.GithubActionRunner, *garov1alpha1.GithubActionRunnerInstance, error) {
+	runnerInstance := &garov1alpha1.GithubActionRunnerInstance{}
+	if err := r.GetClient().Get(ctx, client.ObjectKeyFromObject(pod), runnerInstance); err != nil {
+		return nil, nil, client.IgnoreNotFound(err)
+	}
+	controller := metav1.GetControllerOf(runnerInstance)
+	record := runnerInstance.Status.Pool
+	if controller == nil || controller.UID != pod.UID || record == nil || record.UID != owner.UID {
+		return nil, nil, nil
+	}
+
+	pool := &garov1alpha1.GithubActionRunner{
+		ObjectMeta: metav1.ObjectMeta{Name: owner.Name, Namespace: pod.Namespace, UID: owner.UID},
+		Spec: garov1alpha1.GithubActionRunnerSpec{
+			Organization:      record.Organization,
+			Repository:        record.Repository,
+			CredentialsSource: record.CredentialsSource,
+			AppSecretRef:      record.AppSecretRef,
+			InstallationID:    record.InstallationID,
+		},
+	}
+	if record.TokenRef != nil {
+		pool.Spec.TokenRef = *record.TokenRef
+	}
+
+	return pool, runnerInstance, nil
+}
+
+// RunnerPodReconciler releases runner pods being deleted whose pool is gone, which the GithubActionRunnerReconciler
+// can no longer do. Their runner is unregistered with the pool recorded in the status of their runner instance.
+type RunnerPodReconciler struct {
+	util.ReconcilerBase
+	Log       logr.Logger
+	GithubAPI githubapi.IRunnerAPI
+	// CredentialsProviders read credentials from sources other than Kubernetes Secrets
+	CredentialsProviders map[garov1alpha1.CredentialsSource]credentialprovider.Provider
+	// ForceReleaseAfter is how long after their deletion pods are released even if their runner could not be
+	// unregistered, zero never releases them
+	ForceReleaseAfter time.Duration
+}
+
+// Reconcile unregisters the runner of a deleted pod whose pool is gone and removes its finalizer
+func (r *RunnerPodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("pod", req.NamespacedName)
+	ctx = withResolvedCredentials(logr.NewContext(ctx, logger))
+
+	pod := &corev1.Pod{}
+	if err := r.GetClient().Get(ctx, req.NamespacedName, pod); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if !util.IsBeingDeleted(pod) || !util.HasFinalizer(pod, finalizer) {
+		return reconcile.Result{}, nil
+	}
+	// only pods created by a pool, labels and annotations are writable by anyone who can edit the pod
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind != "GithubActionRunner" || owner.APIVersion != garov1alpha1.GroupVersion.String() {
+		return reconcile.Result{}, nil
+	}
+
+	pool := &garov1alpha1.GithubActionRunner{}
+	err := r.GetClient().Get(ctx, client.ObjectKey{Namespace: pod.Namespace, Name: owner.Name}, pool)
+	if err == nil && pool.UID == owner.UID {
+		// the pool unregisters the runners of its pods, also while paused
+		return r.releaseLater(ctx, pod, fmt.Sprintf("pool %s did not unregister its runner", pool.Name), 0)
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+
+	pool, runnerInstance, err := r.recordedPool(ctx, pod, owner)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if pool == nil {
+		return r.releaseLater(ctx, pod, "pod has no record of its pool", 0)
+	}
+	// the operator-wide app is only for pools that exist, a recorded pool must reference its own credentials
+	if credentialsSecretName(&pool.Spec) == "" {
+		return r.releaseLater(ctx, pod, "recorded pool has no credentials reference", 0)
+	}
+
+	pools := &GithubActionRunnerReconciler{ReconcilerBase: r.ReconcilerBase, Log: r.Log, GithubAPI: r.GithubAPI, CredentialsProviders: r.CredentialsProviders}
+	runner, err := r.findRunner(ctx, pools, pool, pod, runnerInstance)
+	if err == nil {
+		err = pools.unregisterRunner(ctx, pool, podRunnerPair{pod: *pod, runner: runner})
+	}
+	if err != nil {
+		logger.Error(err, "Failed to unregister runner of pod whose pool is gone")
+		return r.releaseLater(ctx, pod, err.Error(), unregisterRetryPeriod)
+	}
+	logger.Info("Released pod whose pool is gone", "runner", runner.GetID())
+
+	return reconcile.Result{}, nil
+}
+
+// findRunner returns the runner of the pod, by the ID recorded on its runner instance or else by its name. A runner that
+// is not found was never registered and is returned empty.
+func (r *RunnerPodReconciler) findRunner(ctx context.Context, pools *GithubActionRunnerReconciler, pool *garov1alpha1.GithubActionRunner, pod *corev1.Pod, runnerInstance *garov1alpha1.GithubActionRunnerInstance) (github.Runner, error) {
+	if runnerInstance.Status.RunnerID != 0 {
+		return github.Runner{ID: github.Int64(runnerInstance.Status.RunnerID), Name: github.String(pod.Name)}, nil
+	}
+
+	credentials, err := pools.credentialsForRef(ctx, pool)
+	if err != nil {
+		return github.Runner{}, err
+	}
+	runners, err := r.GithubAPI.GetRunners(ctx, pool.Spec.Organization, pool.Spec.Repository, credentials)
+	if err != nil {
+		return github.Runner{}, err
+	}
+	runner, found := lo.Find(runners, func(runner *github.Runner) bool {
+		return runner.GetName() == pod.Name
+	})
+	if !found {
+		return github.Runner{}, nil
+	}
+
+	return *runner, nil
+}
+
+// releaseLater removes the finalizer of the pod once ForceReleaseAfter passed since its deletion, until then it is
+// retried after retry, or at the force release
+func (r *RunnerPodReconciler) releaseLater(ctx context.Context, pod *corev1.Pod, reason string, retry time.Duration) (reconcile.Result, error) {
+	if r.ForceReleaseAfter <= 0 {
+		return reconcile.Result{RequeueAfter: retry}, nil
+	}
+	untilRelease := time.Until(pod.DeletionTimestamp.Add(r.ForceReleaseAfter))
+	if untilRelease > 0 {
+		if retry > 0 && retry < untilRelease {
+			return reconcile.Result{RequeueAfter: retry}, nil
+		}
+		return reconcile.Result{RequeueAfter: untilRelease}, nil
+	}
+
+	logr.FromContextOrDiscard(ctx).Info("Force releasing pod, its runner may be left registered", "reason", reason)
+	patch := client.MergeFrom(pod.DeepCopy())
+	util.RemoveFinalizer(pod, finalizer)
+	if err := r.GetClient().Patch(ctx, pod, patch); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	r.GetRecorder().Event(pod, corev1.EventTypeWarning, "ForceReleased", fmt.Sprintf("Released after %s, %s", r.ForceReleaseAfter, reason))
+
+	return reconcile.Result{}, nil
+}
+
+// SetupWithManager configures the controller by using the passed mgr
+func (r *RunnerPodReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	finalizing := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return util.IsBeingDeleted(obj) && util.HasFinalizer(obj, finalizer) && metav1.GetControllerOf(obj) != nil
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("runnerpod").
+		For(&corev1.Pod{}, builder.WithPredicates(finalizing)).
+		Complete(r)
+}
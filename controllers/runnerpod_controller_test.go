@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/google/go-github/v59/github"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestRunnerPodReconciler(t *testing.T) {
+	ctx := context.TODO()
+	runner := testRunner()
+	runner.UID = "runnerUID"
+	runner.Spec.TokenRef = corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "someSecret"}, Key: "GH_TOKEN"}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "someSecret", Namespace: runner.Namespace},
+		Data:       map[string][]byte{"GH_TOKEN": []byte("someToken")},
+	}
+	objs := []client.Object{runner, secret}
+	deletedPod := func(name string, owner types.UID, deletedAgo time.Duration, record *v1alpha1.PoolRecord) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         runner.Namespace,
+			UID:               types.UID(name + "UID"),
+			Labels:            map[string]string{poolLabel: runner.Name},
+			Finalizers:        []string{finalizer},
+			DeletionTimestamp: &metav1.Time{Time: time.Now().Add(-deletedAgo)},
+		}}
+		if owner != "" {
+			pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: v1alpha1.GroupVersion.String(), Kind: "GithubActionRunner", Name: "gone", UID: owner, Controller: lo.ToPtr(true)}}
+		}
+		if owner == runner.UID {
+			pod.OwnerReferences[0].Name = runner.Name
+		}
+		objs = append(objs, pod)
+		if record != nil {
+			objs = append(objs, &v1alpha1.GithubActionRunnerInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					Namespace:       runner.Namespace,
+					OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: name, UID: pod.UID, Controller: lo.ToPtr(true)}},
+				},
+				Status: v1alpha1.GithubActionRunnerInstanceStatus{RunnerID: 5, Pool: record},
+			})
+		}
+		return pod
+	}
+	recorded := func(uid types.UID) *v1alpha1.PoolRecord {
+		return &v1alpha1.PoolRecord{UID: uid, Organization: "someOrg", TokenRef: &runner.Spec.TokenRef}
+	}
+	orphan := deletedPod("orphan", "goneUID", time.Second, recorded("goneUID"))
+	recreated := deletedPod("recreated", "goneUID", time.Second, recorded("goneUID"))
+	recreated.OwnerReferences[0].Name = runner.Name
+	unrecorded := deletedPod("unrecorded", "goneUID", time.Second, nil)
+	tampered := deletedPod("tampered", "goneUID", time.Second, recorded("otherUID"))
+	noCredentials := deletedPod("nocredentials", "goneUID", time.Second, &v1alpha1.PoolRecord{UID: "goneUID", Organization: "someOrg"})
+	foreign := deletedPod("foreign", "", time.Hour, nil)
+	owned := deletedPod("owned", runner.UID, time.Second, recorded(runner.UID))
+	stuck := deletedPod("stuck", runner.UID, time.Hour, recorded(runner.UID))
+	unregistered := deletedPod("unregistered", "goneUID", time.Second, recorded("goneUID"))
+	failing := deletedPod("failing", "goneUID", time.Second, recorded("goneUID"))
+
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerList{}, &v1alpha1.GithubActionRunnerInstance{}, &v1alpha1.GithubActionRunnerInstanceList{})
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).Build()
+	api := new(mockAPI)
+	r := &RunnerPodReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, record.NewFakeRecorder(10), nil), Log: zap.New(), GithubAPI: api, ForceReleaseAfter: 10 * time.Minute}
+	reconcilePod := func(pod *corev1.Pod) reconcile.Result {
+		res, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pod)})
+		assert.NoError(t, err)
+		return res
+	}
+	isGone := func(pod *corev1.Pod) bool {
+		return apierrors.IsNotFound(cl.Get(ctx, client.ObjectKeyFromObject(pod), &corev1.Pod{}))
+	}
+
+	// the runner of a pod whose pool is gone is unregistered with the pool recorded on its runner instance
+	assert.Zero(t, reconcilePod(orphan).RequeueAfter)
+	assert.True(t, isGone(orphan))
+
+	// also when a pool of the same name was created since
+	assert.Zero(t, reconcilePod(recreated).RequeueAfter)
+	assert.True(t, isGone(recreated))
+
+	// pods of existing pools are left to them until the force release
+	assert.InDelta(t, 10*time.Minute, reconcilePod(owned).RequeueAfter, float64(time.Minute))
+	assert.False(t, isGone(owned))
+	assert.Zero(t, reconcilePod(stuck).RequeueAfter)
+	assert.True(t, isGone(stuck))
+
+	// pods not created by a pool are none of our business, whatever their labels
+	assert.Zero(t, reconcilePod(foreign).RequeueAfter)
+	assert.False(t, isGone(foreign))
+
+	// records of another pool and records without credentials of their own are not used
+	assert.InDelta(t, 10*time.Minute, reconcilePod(tampered).RequeueAfter, float64(time.Minute))
+	assert.False(t, isGone(tampered))
+	assert.InDelta(t, 10*time.Minute, reconcilePod(noCredentials).RequeueAfter, float64(time.Minute))
+	assert.False(t, isGone(noCredentials))
+
+	// a runner GitHub does not know anymore is unregistered already
+	api.unregisterErr = &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}, Message: "Not Found"}
+	assert.Zero(t, reconcilePod(unregistered).RequeueAfter)
+	assert.True(t, isGone(unregistered))
+
+	// other failures are retried
+	api.unregisterErr = &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusInternalServerError}, Message: "Server Error"}
+	assert.Equal(t, unregisterRetryPeriod, reconcilePod(failing).RequeueAfter)
+	assert.False(t, isGone(failing))
+	api.unregisterErr = nil
+
+	// without a record the runner cannot be unregistered
+	r.ForceReleaseAfter = 0
+	assert.Zero(t, reconcilePod(unrecorded).RequeueAfter)
+	assert.False(t, isGone(unrecorded))
+}
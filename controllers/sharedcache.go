@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/samber/lo"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// sharedCacheLabel marks the claims and warm-up jobs of the shared cache of a pool
+const sharedCacheLabel = "garo.tietoevry.com/shared-cache"
+
+const sharedCacheReadyCondition = "SharedCacheReady"
+
+// reconcileSharedCache starts a warm-up job when the shared cache is due for a refresh, promotes the generation it
+// populated once it succeeded, and removes generations no runner pod uses anymore
+func (r *GithubActionRunnerReconciler) reconcileSharedCache(ctx context.Context, instance *garov1alpha1.GithubActionRunner, podRunnerPairs podRunnerPairList) error {
+	if instance.Spec.SharedCache == nil {
+		instance.Status.SharedCache = nil
+		return nil
+	}
+	if instance.Status.SharedCache == nil {
+		instance.Status.SharedCache = &garov1alpha1.SharedCacheStatus{}
+	}
+	status := instance.Status.SharedCache
+
+	if status.Pending != "" {
+		if err := r.checkWarmUp(ctx, instance, status); err != nil {
+			return err
+		}
+	}
+
+	if status.Pending == "" && sharedCacheRefreshDue(instance, time.Now()) {
+		if err := r.startWarmUp(ctx, instance, status); err != nil {
+			return err
+		}
+	}
+
+	return r.cleanupSharedCache(ctx, instance, podRunnerPairs)
+}
+
+// sharedCacheRefreshDue tells if a new generation of the shared cache should be populated
+func sharedCacheRefreshDue(instance *garov1alpha1.GithubActionRunner, now time.Time) bool {
+	lastWarmUp := instance.Status.SharedCache.LastWarmUpTime
+	return lastWarmUp == nil || !now.Before(lastWarmUp.Add(instance.Spec.SharedCache.RefreshInterval.Duration))
+}
+
+// untilSharedCacheRefresh returns how long until the next refresh of the shared cache is due
+func untilSharedCacheRefresh(instance *garov1alpha1.GithubActionRunner) (time.Duration, bool) {
+	if instance.Spec.SharedCache == nil || instance.Status.SharedCache == nil || instance.Status.SharedCache.LastWarmUpTime == nil {
+		return 0, false
+	}
+
+	return time.Until(instance.Status.SharedCache.LastWarmUpTime.Add(instance.Spec.SharedCache.RefreshInterval.Duration)), true
+}
+
+func (r *GithubActionRunnerReconciler) startWarmUp(ctx context.Context, instance *garov1alpha1.GithubActionRunner, status *garov1alpha1.SharedCacheStatus) error {
+	cache := instance.Spec.SharedCache
+	name := fmt.Sprintf("%s-%s-%d", instance.Name, cache.Name, status.Generation+1)
+
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: instance.Namespace,
+			Labels:    map[string]string{sharedCacheLabel: instance.Name},
+		},
+		Spec: *cache.VolumeClaimSpec.DeepCopy(),
+	}
+	meta := claim.GetObjectMeta()
+	if err := r.addMetaData(instance, &meta); err != nil {
+		return err
+	}
+	if err := r.GetClient().Create(ctx, claim); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	podTemplate := *cache.WarmUp.DeepCopy()
+	podTemplate.Spec.Volumes = append(podTemplate.Spec.Volumes, corev1.Volume{
+		Name: cache.Name,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: name},
+		},
+	})
+	if podTemplate.Spec.RestartPolicy == "" {
+		podTemplate.Spec.RestartPolicy = corev1.RestartPolicyNever
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      warmUpJobName(name),
+			Namespace: instance.Namespace,
+			Labels:    map[string]string{sharedCacheLabel: instance.Name},
+		},
+		Spec: batchv1.JobSpec{Template: podTemplate},
+	}
+	meta = job.GetObjectMeta()
+	if err := r.addMetaData(instance, &meta); err != nil {
+		return err
+	}
+	if err := r.GetClient().Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	logr.FromContextOrDiscard(ctx).Info("Warming up shared cache", "claim", name)
+	status.Generation++
+	status.Pending = name
+	setCondition(instance, sharedCacheReadyCondition, status.Current != "", "WarmingUp", fmt.Sprintf("Populating %s", name))
+
+	return nil
+}
+
+// checkWarmUp promotes the pending generation once its warm-up job succeeded, or drops it if the job failed
+func (r *GithubActionRunnerReconciler) checkWarmUp(ctx context.Context, instance *garov1alpha1.GithubActionRunner, status *garov1alpha1.SharedCacheStatus) error {
+	job := &batchv1.Job{}
+	err := r.GetClient().Get(ctx, client.ObjectKey{Namespace: instance.Namespace, Name: warmUpJobName(status.Pending)}, job)
+	if apierrors.IsNotFound(err) {
+		// deleted behind our back, start over
+		status.Pending = ""
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case job.Status.Succeeded > 0:
+		logr.FromContextOrDiscard(ctx).Info("Shared cache warmed up", "claim", status.Pending)
+		status.Current = status.Pending
+		setCondition(instance, sharedCacheReadyCondition, true, "Ready", fmt.Sprintf("Runner pods mount %s", status.Current))
+	case jobFailed(job):
+		r.GetRecorder().Event(instance, corev1.EventTypeWarning, "WarmUpFailed", fmt.Sprintf("Job %s failed, keeping the previous shared cache", job.Name))
+		setCondition(instance, sharedCacheReadyCondition, status.Current != "", "WarmUpFailed", fmt.Sprintf("Job %s failed", job.Name))
+	default:
+		return nil
+	}
+
+	status.Pending = ""
+	status.LastWarmUpTime = &metav1.Time{Time: time.Now()}
+
+	return nil
+}
+
+func jobFailed(job *batchv1.Job) bool {
+	_, found := lo.Find(job.Status.Conditions, func(condition batchv1.JobCondition) bool {
+		return condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue
+	})
+	return found
+}
+
+func warmUpJobName(claimName string) string {
+	return claimName + "-warmup"
+}
+
+// addSharedCache mounts the current generation of the shared cache into the runner pod.
+// Until the first warm-up succeeded an empty directory is mounted instead, so the pod template stays valid.
+func addSharedCache(spec *corev1.PodSpec, instance *garov1alpha1.GithubActionRunner) {
+	if instance.Spec.SharedCache == nil {
+		return
+	}
+
+	if instance.Status.SharedCache == nil || instance.Status.SharedCache.Current == "" {
+		addEmptyDir(spec, instance.Spec.SharedCache.Name)
+		return
+	}
+
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: instance.Spec.SharedCache.Name,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: instance.Status.SharedCache.Current,
+				ReadOnly:  true,
+			},
+		},
+	})
+}
+
+// cleanupSharedCache deletes generations of the shared cache which are neither current, pending nor used by a runner pod
+func (r *GithubActionRunnerReconciler) cleanupSharedCache(ctx context.Context, instance *garov1alpha1.GithubActionRunner, podRunnerPairs podRunnerPairList) error {
+	claimList := &corev1.PersistentVolumeClaimList{}
+	if err := r.GetClient().List(ctx, claimList, client.InNamespace(instance.Namespace), client.MatchingLabels{sharedCacheLabel: instance.Name}); err != nil {
+		return err
+	}
+
+	inUse := map[string]bool{instance.Status.SharedCache.Current: true, instance.Status.SharedCache.Pending: true}
+	for _, pod := range podRunnerPairs.podList.Items {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil {
+				inUse[volume.PersistentVolumeClaim.ClaimName] = true
+			}
+		}
+	}
+
+	for i := range claimList.Items {
+		claim := &claimList.Items[i]
+		if inUse[claim.Name] || !util.IsOwner(instance, claim) {
+			continue
+		}
+
+		logr.FromContextOrDiscard(ctx).Info("Deleting unused shared cache", "claim", claim.Name)
+		// the job pod references the claim, delete it first
+		job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: claim.Namespace, Name: warmUpJobName(claim.Name)}}
+		if err := r.GetClient().Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err := r.DeleteResourceIfExists(ctx, claim); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
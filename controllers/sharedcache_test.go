@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func claimNames(t *testing.T, cl client.Client) []string {
+	claims := &corev1.PersistentVolumeClaimList{}
+	assert.NoError(t, cl.List(context.TODO(), claims))
+	return lo.Map(claims.Items, func(claim corev1.PersistentVolumeClaim, _ int) string { return claim.Name })
+}
+
+func completeWarmUp(t *testing.T, cl client.Client, name string, condition batchv1.JobConditionType) {
+	job := &batchv1.Job{}
+	assert.NoError(t, cl.Get(context.TODO(), client.ObjectKey{Namespace: "someNamespace", Name: name}, job))
+	if condition == batchv1.JobComplete {
+		job.Status.Succeeded = 1
+	}
+	job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+	assert.NoError(t, cl.Status().Update(context.TODO(), job))
+}
+
+func TestSharedCache(t *testing.T) {
+	ctx := context.TODO()
+	instance := testRunner()
+	instance.UID = "someUID"
+	instance.Kind = "GithubActionRunner"
+	instance.Spec.SharedCache = &v1alpha1.SharedCacheSpec{
+		Name: "deps",
+		VolumeClaimSpec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+		},
+		WarmUp: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "warmup", Image: "someWarmUpImage"}}},
+		},
+		RefreshInterval: metav1.Duration{Duration: time.Hour},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{})
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(instance).WithStatusSubresource(&batchv1.Job{}).Build()
+	r := &GithubActionRunnerReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, record.NewFakeRecorder(10), nil), Log: zap.New()}
+	noPods := from(&corev1.PodList{}, nil)
+
+	// the first generation is warmed up, runners get an empty directory meanwhile
+	assert.NoError(t, r.reconcileSharedCache(ctx, instance, noPods))
+	assert.Equal(t, "somerunner-deps-1", instance.Status.SharedCache.Pending)
+	assert.Equal(t, []string{"somerunner-deps-1"}, claimNames(t, cl))
+	job := &batchv1.Job{}
+	assert.NoError(t, cl.Get(ctx, client.ObjectKey{Namespace: "someNamespace", Name: "somerunner-deps-1-warmup"}, job))
+	assert.Equal(t, corev1.RestartPolicyNever, job.Spec.Template.Spec.RestartPolicy)
+	assert.Equal(t, "somerunner-deps-1", job.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim.ClaimName)
+	assert.False(t, meta.IsStatusConditionTrue(instance.Status.Conditions, sharedCacheReadyCondition))
+
+	spec, err := r.runnerPodSpec(ctx, instance)
+	assert.NoError(t, err)
+	assert.NotNil(t, spec.Volumes[0].EmptyDir)
+
+	// nothing changes while the job runs
+	assert.NoError(t, r.reconcileSharedCache(ctx, instance, noPods))
+	assert.Equal(t, "somerunner-deps-1", instance.Status.SharedCache.Pending)
+
+	completeWarmUp(t, cl, "somerunner-deps-1-warmup", batchv1.JobComplete)
+	assert.NoError(t, r.reconcileSharedCache(ctx, instance, noPods))
+	assert.Equal(t, "somerunner-deps-1", instance.Status.SharedCache.Current)
+	assert.Empty(t, instance.Status.SharedCache.Pending)
+	assert.True(t, meta.IsStatusConditionTrue(instance.Status.Conditions, sharedCacheReadyCondition))
+
+	spec, err = r.runnerPodSpec(ctx, instance)
+	assert.NoError(t, err)
+	assert.Equal(t, &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "somerunner-deps-1", ReadOnly: true}, spec.Volumes[0].PersistentVolumeClaim)
+	runnerPod := corev1.Pod{Spec: spec}
+
+	// a failed refresh keeps the current generation
+	instance.Status.SharedCache.LastWarmUpTime = &metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+	assert.NoError(t, r.reconcileSharedCache(ctx, instance, noPods))
+	assert.Equal(t, "somerunner-deps-2", instance.Status.SharedCache.Pending)
+	completeWarmUp(t, cl, "somerunner-deps-2-warmup", batchv1.JobFailed)
+	assert.NoError(t, r.reconcileSharedCache(ctx, instance, noPods))
+	assert.Equal(t, "somerunner-deps-1", instance.Status.SharedCache.Current)
+	assert.Equal(t, []string{"somerunner-deps-1"}, claimNames(t, cl))
+
+	// a successful refresh swaps generations, the previous one is kept while pods use it
+	instance.Status.SharedCache.LastWarmUpTime = &metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+	assert.NoError(t, r.reconcileSharedCache(ctx, instance, noPods))
+	completeWarmUp(t, cl, "somerunner-deps-3-warmup", batchv1.JobComplete)
+	pods := from(&corev1.PodList{Items: []corev1.Pod{runnerPod}}, nil)
+	assert.NoError(t, r.reconcileSharedCache(ctx, instance, pods))
+	assert.Equal(t, "somerunner-deps-3", instance.Status.SharedCache.Current)
+	assert.ElementsMatch(t, []string{"somerunner-deps-1", "somerunner-deps-3"}, claimNames(t, cl))
+
+	assert.NoError(t, r.reconcileSharedCache(ctx, instance, noPods))
+	assert.Equal(t, []string{"somerunner-deps-3"}, claimNames(t, cl))
+	assert.Error(t, cl.Get(ctx, client.ObjectKey{Namespace: "someNamespace", Name: "somerunner-deps-1-warmup"}, job))
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// poolFinalizer keeps a deleted GithubActionRunner until its runners are unregistered, as unregistering the runner
+// of a pod needs the CR
+const poolFinalizer = "garo.tietoevry.com/pool-teardown"
+
+// ensurePoolFinalizer adds the pool finalizer to the CR, patching its metadata only
+func (r *GithubActionRunnerReconciler) ensurePoolFinalizer(ctx context.Context, instance *garov1alpha1.GithubActionRunner) error {
+	if util.HasFinalizer(instance, poolFinalizer) {
+		return nil
+	}
+	patch := client.MergeFrom(instance.DeepCopy())
+	util.AddFinalizer(instance, poolFinalizer)
+
+	return r.GetClient().Patch(ctx, instance, patch)
+}
+
+// teardown drains a deleted instance: idle runners are unregistered and their pods deleted, busy runners are waited
+// for, pods whose runner is not listed are deleted as they will not take jobs anymore. Once no pods are left the
+// registration token secret is deleted and the CR released.
+func (r *GithubActionRunnerReconciler) teardown(ctx context.Context, instance *garov1alpha1.GithubActionRunner) (reconcile.Result, error) {
+	if !util.HasFinalizer(instance, poolFinalizer) {
+		return reconcile.Result{}, nil
+	}
+	logger := logr.FromContextOrDiscard(ctx)
+
+	// without its class or credentials the pool cannot list its runners, e.g. when a namespace is deleted
+	if _, err := r.runnerSpec(ctx, instance); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return r.manageOutcome(ctx, instance, err)
+		}
+		return r.releaseUndrained(ctx, instance, fmt.Sprintf("class %s not found", instance.Spec.ClassName))
+	}
+	if _, err := r.credentialsForRef(ctx, instance); err != nil {
+		return r.releaseUndrained(ctx, instance, err.Error())
+	}
+
+	podRunnerPairs, err := r.getPodRunnerPairs(ctx, instance)
+	if err != nil {
+		return r.manageOutcome(ctx, instance, err)
+	}
+	if err := r.handleFinalization(ctx, instance, podRunnerPairs); err != nil {
+		return r.manageOutcome(ctx, instance, err)
+	}
+
+	removed := 0
+	for _, pair := range podRunnerPairs.getIdles(instance.Spec.DeletionOrder, 0) {
+		ok, err := r.removeIdleRunner(ctx, instance, pair, scaleReasonDrain)
+		if err != nil {
+			return r.manageOutcome(ctx, instance, err)
+		}
+		if ok {
+			removed++
+		}
+	}
+
+	// pods being deleted count until they are gone
+	if remaining := podRunnerPairs.numPods() - removed; remaining > 0 {
+		logger.Info("Waiting for runners to finish before releasing the pool", "remaining", remaining)
+		setCondition(instance, drainingCondition, true, "Deleting", fmt.Sprintf("Waiting for %d runners to finish before deleting the pool", remaining))
+		return r.manageOutcome(ctx, instance, nil)
+	}
+
+	logger.Info("Runners unregistered, releasing the pool")
+	return r.releasePool(ctx, instance)
+}
+
+// releaseUndrained deletes the pods of an instance whose runners cannot be unregistered by the pool and releases the
+// CR. The pods keep their finalizer, their runners are unregistered by the RunnerPodReconciler with what they recorded.
+func (r *GithubActionRunnerReconciler) releaseUndrained(ctx context.Context, instance *garov1alpha1.GithubActionRunner, reason string) (reconcile.Result, error) {
+	podList, err := r.listRelatedPods(ctx, instance)
+	if err != nil {
+		return r.manageOutcome(ctx, instance, err)
+	}
+	for i := range podList.Items {
+		if err := r.DeleteResourceIfExists(ctx, &podList.Items[i]); err != nil {
+			return r.manageOutcome(ctx, instance, err)
+		}
+	}
+
+	logr.FromContextOrDiscard(ctx).Info("Releasing the pool without unregistering its runners", "reason", reason, "pods", len(podList.Items))
+	r.GetRecorder().Event(instance, corev1.EventTypeWarning, "ReleasedUndrained", fmt.Sprintf("Deleted %d pods without waiting for their runners, %s", len(podList.Items), reason))
+	return r.releasePool(ctx, instance)
+}
+
+// releasePool deletes the registration token secret and removes the pool finalizer
+func (r *GithubActionRunnerReconciler) releasePool(ctx context.Context, instance *garov1alpha1.GithubActionRunner) (reconcile.Result, error) {
+	key := r.getRegistrationSecretObjectKey(instance)
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+	if err := r.DeleteResourceIfExists(ctx, secret); err != nil {
+		return r.manageOutcome(ctx, instance, err)
+	}
+
+	patch := client.MergeFrom(instance.DeepCopy())
+	util.RemoveFinalizer(instance, poolFinalizer)
+	if err := r.GetClient().Patch(ctx, instance, patch); err != nil {
+		return r.manageOutcome(ctx, instance, err)
+	}
+
+	return reconcile.Result{}, nil
+}
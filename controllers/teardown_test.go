@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/google/go-github/v59/github"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestTeardown(t *testing.T) {
+	ctx := context.TODO()
+	runner := testRunner()
+	runner.UID = "someUID"
+	runner.TypeMeta = metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "GithubActionRunner"}
+	regToken := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "somerunner-regtoken", Namespace: runner.Namespace}}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerList{}, &v1alpha1.GithubActionRunnerInstance{}, &v1alpha1.GithubActionRunnerQuota{}, &v1alpha1.GithubActionRunnerQuotaList{})
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(runner, regToken).WithStatusSubresource(&v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerInstance{}).Build()
+	api := new(mockAPI)
+	r := &GithubActionRunnerReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, record.NewFakeRecorder(20), nil), Log: zap.New(), GithubAPI: api}
+
+	assert.NoError(t, r.ensurePoolFinalizer(ctx, runner))
+	assert.Equal(t, 2, lo.Must(r.scaleUp(ctx, 2, runner)))
+	pods := lo.Must(r.listRelatedPods(ctx, runner))
+	runners := lo.Map(pods.Items, func(pod corev1.Pod, i int) *github.Runner {
+		return &github.Runner{ID: github.Int64(int64(i + 1)), Name: github.String(pod.Name), Status: github.String("online"), Busy: github.Bool(i == 0)}
+	})
+	api.On("GetRunners", "someOrg", "someRepo", mock.Anything).Return(runners, nil).Once()
+	assert.NoError(t, cl.Delete(ctx, runner))
+
+	// the idle runner is removed and the CR kept while the busy runner finishes its job
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(runner)}
+	_, err := r.Reconcile(ctx, req)
+	assert.NoError(t, err)
+	assert.Len(t, lo.Must(r.listRelatedPods(ctx, runner)).Items, 1)
+	assert.NoError(t, cl.Get(ctx, req.NamespacedName, runner))
+	assert.Equal(t, "Deleting", meta.FindStatusCondition(runner.Status.Conditions, drainingCondition).Reason)
+
+	// once the job finished the last runner is removed and the CR released
+	api.On("GetRunners", "someOrg", "someRepo", mock.Anything).Return([]*github.Runner{{ID: github.Int64(1), Name: github.String(pods.Items[0].Name), Status: github.String("online"), Busy: github.Bool(false)}}, nil).Once()
+	_, err = r.Reconcile(ctx, req)
+	assert.NoError(t, err)
+	assert.Empty(t, lo.Must(r.listRelatedPods(ctx, runner)).Items)
+	assert.True(t, apierrors.IsNotFound(cl.Get(ctx, client.ObjectKeyFromObject(regToken), regToken)))
+	assert.True(t, apierrors.IsNotFound(cl.Get(ctx, req.NamespacedName, runner)))
+}
+
+func TestTeardownWithoutCredentials(t *testing.T) {
+	ctx := context.TODO()
+	runner := testRunner()
+	runner.UID = "someUID"
+	runner.TypeMeta = metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "GithubActionRunner"}
+	runner.Spec.TokenRef = corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "someSecret"}, Key: "GH_TOKEN"}
+	token := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "someSecret", Namespace: runner.Namespace},
+		Data:       map[string][]byte{"GH_TOKEN": []byte("someToken")},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerList{}, &v1alpha1.GithubActionRunnerInstance{}, &v1alpha1.GithubActionRunnerQuota{}, &v1alpha1.GithubActionRunnerQuotaList{})
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(runner, token).WithStatusSubresource(&v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerInstance{}).Build()
+	recorder := record.NewFakeRecorder(20)
+	r := &GithubActionRunnerReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, recorder, nil), Log: zap.New(), GithubAPI: new(mockAPI)}
+
+	assert.NoError(t, r.ensurePoolFinalizer(ctx, runner))
+	assert.Equal(t, 2, lo.Must(r.scaleUp(ctx, 2, runner)))
+
+	// the secret is deleted first, e.g. along with the namespace
+	assert.NoError(t, cl.Delete(ctx, token))
+	assert.NoError(t, cl.Delete(ctx, runner))
+
+	// the pods are deleted and left to the RunnerPodReconciler, the CR is released right away
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(runner)}
+	_, err := r.Reconcile(ctx, req)
+	assert.NoError(t, err)
+	assert.True(t, apierrors.IsNotFound(cl.Get(ctx, req.NamespacedName, &v1alpha1.GithubActionRunner{})))
+	pods := lo.Must(r.listRelatedPods(ctx, runner)).Items
+	assert.Len(t, pods, 2)
+	for _, pod := range pods {
+		assert.True(t, util.IsBeingDeleted(&pod))
+		assert.True(t, util.HasFinalizer(&pod, finalizer))
+	}
+	events := make([]string, 0, len(recorder.Events))
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	assert.True(t, lo.ContainsBy(events, func(event string) bool { return strings.Contains(event, "ReleasedUndrained") }))
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// slotLabel records the slot of a runner pod, and of the volume claims created for that slot
+const slotLabel = "garo.tietoevry.com/slot"
+
+// usesSlots tells if pods of the pool are assigned a slot
+func usesSlots(instance *garov1alpha1.GithubActionRunner) bool {
+	return len(instance.Spec.VolumeClaimTemplates) > 0 || instance.Spec.PodNaming == garov1alpha1.OrdinalPodNaming
+}
+
+// podSlot returns the slot of the pod, or -1 if it has none
+func podSlot(pod *corev1.Pod) int {
+	slot, err := strconv.Atoi(pod.GetLabels()[slotLabel])
+	if err != nil {
+		return -1
+	}
+
+	return slot
+}
+
+// freeSlots returns the lowest amount slots not held by pods of the pool, pods being deleted still hold their slot
+func freeSlots(pods []corev1.Pod, amount int) []int {
+	used := lo.SliceToMap(pods, func(pod corev1.Pod) (int, bool) {
+		return podSlot(&pod), true
+	})
+
+	slots := make([]int, 0, amount)
+	for slot := 0; len(slots) < amount; slot++ {
+		if !used[slot] {
+			slots = append(slots, slot)
+		}
+	}
+
+	return slots
+}
+
+func volumeClaimName(instance *garov1alpha1.GithubActionRunner, template corev1.PersistentVolumeClaim, slot int) string {
+	return fmt.Sprintf("%s-%s-%d", template.Name, instance.Name, slot)
+}
+
+// ensureVolumeClaims creates the claims of the slot that do not exist yet, and adds them as volumes to the pod spec
+func (r *GithubActionRunnerReconciler) ensureVolumeClaims(ctx context.Context, instance *garov1alpha1.GithubActionRunner, slot int, spec *corev1.PodSpec) error {
+	for _, template := range instance.Spec.VolumeClaimTemplates {
+		claim := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        volumeClaimName(instance, template, slot),
+				Namespace:   instance.Namespace,
+				Labels:      lo.Assign(template.Labels, map[string]string{slotLabel: strconv.Itoa(slot)}),
+				Annotations: template.Annotations,
+			},
+			Spec: *template.Spec.DeepCopy(),
+		}
+		meta := claim.GetObjectMeta()
+		if err := r.addMetaData(instance, &meta); err != nil {
+			return err
+		}
+
+		if err := r.GetClient().Create(ctx, claim); err != nil && !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+
+		spec.Volumes = append(spec.Volumes, corev1.Volume{
+			Name: template.Name,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim.Name},
+			},
+		})
+	}
+
+	return nil
+}
+
+// cleanupVolumeClaims deletes the claims of slots no pod of the pool holds
+func (r *GithubActionRunnerReconciler) cleanupVolumeClaims(ctx context.Context, instance *garov1alpha1.GithubActionRunner, podRunnerPairs podRunnerPairList) error {
+	claimList := &corev1.PersistentVolumeClaimList{}
+	if err := r.GetClient().List(ctx, claimList, client.InNamespace(instance.Namespace), client.MatchingLabels{poolLabel: instance.Name}); err != nil {
+		return err
+	}
+
+	used := lo.SliceToMap(podRunnerPairs.podList.Items, func(pod corev1.Pod) (int, bool) {
+		return podSlot(&pod), true
+	})
+	for i := range claimList.Items {
+		claim := &claimList.Items[i]
+		slot, err := strconv.Atoi(claim.Labels[slotLabel])
+		if err != nil || !util.IsOwner(instance, claim) || used[slot] {
+			continue
+		}
+
+		logr.FromContextOrDiscard(ctx).Info("Deleting volume claim of unused slot", "claim", claim.Name, "slot", slot)
+		if err := r.DeleteResourceIfExists(ctx, claim); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/redhat-cop/operator-utils/pkg/util"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestFreeSlots(t *testing.T) {
+	pods := []corev1.Pod{slotPod("2"), slotPod("0"), slotPod("garbage")}
+	assert.Equal(t, []int{1, 3, 4}, freeSlots(pods, 3))
+	assert.Empty(t, freeSlots(pods, 0))
+}
+
+func slotPod(slot string) corev1.Pod {
+	pod := corev1.Pod{}
+	pod.Labels = map[string]string{slotLabel: slot}
+	return pod
+}
+
+func TestVolumeClaimsPerSlot(t *testing.T) {
+	ctx := context.TODO()
+	instance := testRunner()
+	instance.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{{
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+			},
+		},
+	}}
+	instance.Spec.VolumeClaimTemplates[0].Name = "cache"
+	instance.UID = "someUID"
+	instance.Kind = "GithubActionRunner"
+
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerInstance{}, &v1alpha1.GithubActionRunnerQuotaList{})
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(instance).WithStatusSubresource(&v1alpha1.GithubActionRunnerInstance{}).Build()
+	r := &GithubActionRunnerReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, record.NewFakeRecorder(10), nil), Log: zap.New()}
+
+	assert.Equal(t, 2, lo.Must(r.scaleUp(ctx, 2, instance)))
+	pods, err := r.listRelatedPods(ctx, instance)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"0", "1"}, lo.Map(pods.Items, func(pod corev1.Pod, _ int) string { return pod.Labels[slotLabel] }))
+	for _, pod := range pods.Items {
+		volume, found := lo.Find(pod.Spec.Volumes, func(volume corev1.Volume) bool { return volume.Name == "cache" })
+		assert.True(t, found)
+		assert.Equal(t, "cache-somerunner-"+pod.Labels[slotLabel], volume.PersistentVolumeClaim.ClaimName)
+	}
+	assert.Empty(t, instance.Spec.PodTemplateSpec.Labels[slotLabel])
+
+	claims := &corev1.PersistentVolumeClaimList{}
+	assert.NoError(t, cl.List(ctx, claims))
+	assert.ElementsMatch(t, []string{"cache-somerunner-0", "cache-somerunner-1"}, lo.Map(claims.Items, func(claim corev1.PersistentVolumeClaim, _ int) string { return claim.Name }))
+
+	// a replaced pod reuses the claim of its slot
+	deletePodInSlot(t, cl, pods, "0")
+	assert.Equal(t, 1, lo.Must(r.scaleUp(ctx, 1, instance)))
+	assert.NoError(t, cl.List(ctx, claims))
+	assert.Len(t, claims.Items, 2)
+
+	// the claim of a slot no pod holds is deleted, also below the size of the pool
+	pods, err = r.listRelatedPods(ctx, instance)
+	assert.NoError(t, err)
+	deletePodInSlot(t, cl, pods, "0")
+	pods, err = r.listRelatedPods(ctx, instance)
+	assert.NoError(t, err)
+	assert.NoError(t, r.cleanupVolumeClaims(ctx, instance, from(pods, nil)))
+	assert.NoError(t, cl.List(ctx, claims, client.InNamespace(instance.Namespace)))
+	assert.Equal(t, []string{"cache-somerunner-1"}, lo.Map(claims.Items, func(claim corev1.PersistentVolumeClaim, _ int) string { return claim.Name }))
+}
+
+func deletePodInSlot(t *testing.T, cl client.Client, pods *corev1.PodList, slot string) {
+	pod, found := lo.Find(pods.Items, func(pod corev1.Pod) bool { return pod.Labels[slotLabel] == slot })
+	assert.True(t, found)
+	pod.Finalizers = nil
+	assert.NoError(t, cl.Update(context.TODO(), &pod))
+	assert.NoError(t, cl.Delete(context.TODO(), &pod))
+}
+
+func TestOrdinalPodNaming(t *testing.T) {
+	ctx := context.TODO()
+	instance := testRunner()
+	instance.UID = "someUID"
+	instance.Kind = "GithubActionRunner"
+	instance.Spec.PodNaming = v1alpha1.OrdinalPodNaming
+
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerInstance{}, &v1alpha1.GithubActionRunnerQuotaList{})
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(instance).WithStatusSubresource(&v1alpha1.GithubActionRunnerInstance{}).Build()
+	r := &GithubActionRunnerReconciler{ReconcilerBase: util.NewReconcilerBase(cl, s, nil, record.NewFakeRecorder(10), nil), Log: zap.New()}
+	podNames := func() []string {
+		pods, err := r.listRelatedPods(ctx, instance)
+		assert.NoError(t, err)
+		return lo.Map(pods.Items, func(pod corev1.Pod, _ int) string { return pod.Name })
+	}
+
+	assert.Equal(t, 3, lo.Must(r.scaleUp(ctx, 3, instance)))
+	assert.ElementsMatch(t, []string{"somerunner-0", "somerunner-1", "somerunner-2"}, podNames())
+
+	// the lowest free slot is filled first
+	pods, err := r.listRelatedPods(ctx, instance)
+	assert.NoError(t, err)
+	deletePodInSlot(t, cl, pods, "1")
+	assert.Equal(t, 2, lo.Must(r.scaleUp(ctx, 2, instance)))
+	assert.ElementsMatch(t, []string{"somerunner-0", "somerunner-1", "somerunner-2", "somerunner-3"}, podNames())
+}
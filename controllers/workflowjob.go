@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/google/go-github/v59/github"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const workflowJobEvent = "workflow_job"
+
+// runnerInstancePodIndex indexes GithubActionRunnerInstances by the name of the pod hosting their runner
+const runnerInstancePodIndex = "spec.podName"
+
+func indexRunnerInstancePod(obj client.Object) []string {
+	return []string{obj.(*garov1alpha1.GithubActionRunnerInstance).Spec.PodName}
+}
+
+// IndexRunnerInstancePods registers the index the WorkflowJobHandler finds the runner of a job with
+func IndexRunnerInstancePods(ctx context.Context, indexer client.FieldIndexer) error {
+	return indexer.IndexField(ctx, &garov1alpha1.GithubActionRunnerInstance{}, runnerInstancePodIndex, indexRunnerInstancePod)
+}
+
+// WorkflowJobHandler receives GitHub workflow_job webhook deliveries and records queue-wait and duration
+// metrics for jobs picked up by runners of the pools managed by the operator.
+type WorkflowJobHandler struct {
+	Client client.Client
+	Log    logr.Logger
+	// Secret configured on the GitHub webhook, used to validate deliveries. Deliveries are rejected without one.
+	Secret []byte
+}
+
+// ServeHTTP handles a single webhook delivery
+func (h *WorkflowJobHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// go-github accepts unsigned deliveries when the secret is empty
+	if len(h.Secret) == 0 {
+		h.Log.Info("Rejecting webhook delivery", "reason", "no webhook secret configured")
+		http.Error(w, "no webhook secret configured", http.StatusForbidden)
+		return
+	}
+	payload, err := github.ValidatePayload(req, h.Secret)
+	if err != nil {
+		h.Log.Info("Rejecting webhook delivery", "reason", err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	eventType := github.WebHookType(req)
+	if eventType != workflowJobEvent {
+		// other events are acknowledged but ignored
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	event, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// GitHub does not redeliver failed deliveries, an accepted event is acknowledged even if it could not be recorded
+	if err := h.handleWorkflowJob(req.Context(), event.(*github.WorkflowJobEvent)); err != nil {
+		h.Log.Error(err, "Failed to handle workflow_job event")
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *WorkflowJobHandler) handleWorkflowJob(ctx context.Context, event *github.WorkflowJobEvent) error {
+	job := event.GetWorkflowJob()
+	// jobs are only attributable to a pool once a runner has picked them up
+	if job.GetRunnerName() == "" {
+		return nil
+	}
+
+	runnerInstance, err := h.findRunnerInstance(ctx, job.GetRunnerName(), event.GetRepo())
+	if err != nil || runnerInstance == nil {
+		return err
+	}
+
+	pool := runnerInstance.Spec.Pool
+	switch event.GetAction() {
+	case "in_progress":
+		if job.CreatedAt != nil && job.StartedAt != nil {
+			jobQueueWait.WithLabelValues(runnerInstance.Namespace, pool).Observe(job.StartedAt.Sub(job.CreatedAt.Time).Seconds())
+		}
+		// every replica receives deliveries, a merge patch without resource version does not conflict with them or
+		// the controller
+		patch := client.MergeFrom(runnerInstance.DeepCopy())
+		runnerInstance.Status.JobURL = job.GetHTMLURL()
+		runnerInstance.Status.RunURL = runURL(job)
+		return h.Client.Status().Patch(ctx, runnerInstance, patch)
+	case "completed":
+		if job.StartedAt != nil && job.CompletedAt != nil {
+			jobDuration.WithLabelValues(runnerInstance.Namespace, pool).Observe(job.CompletedAt.Sub(job.StartedAt.Time).Seconds())
+		}
+		jobsServed.WithLabelValues(runnerInstance.Namespace, pool, job.GetConclusion()).Inc()
+	}
+
+	return nil
+}
+
+// findRunnerInstance looks up the runner instance recording the runner with the given name which registers at the
+// repository of the job, nil if not one of ours. Ordinal pod names repeat across namespaces, runners of pools of the
+// same scope with the same name cannot be told apart and are ignored.
+func (h *WorkflowJobHandler) findRunnerInstance(ctx context.Context, runnerName string, repo *github.Repository) (*garov1alpha1.GithubActionRunnerInstance, error) {
+	instanceList := &garov1alpha1.GithubActionRunnerInstanceList{}
+	if err := h.Client.List(ctx, instanceList, client.MatchingFields{runnerInstancePodIndex: runnerName}); err != nil {
+		return nil, err
+	}
+
+	var matches []garov1alpha1.GithubActionRunnerInstance
+	for _, item := range instanceList.Items {
+		pool := &garov1alpha1.GithubActionRunner{}
+		if err := h.Client.Get(ctx, client.ObjectKey{Namespace: item.Namespace, Name: item.Spec.Pool}, pool); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if registersAt(pool, repo) {
+			matches = append(matches, item)
+		}
+	}
+	if len(matches) > 1 {
+		h.Log.Info("Ignoring job of a runner name shared by several pools", "runner", runnerName, "repository", repo.GetFullName())
+	}
+	if len(matches) != 1 {
+		return nil, nil
+	}
+
+	return &matches[0], nil
+}
+
+// registersAt tells if runners of the pool can pick up jobs of the repository
+func registersAt(pool *garov1alpha1.GithubActionRunner, repo *github.Repository) bool {
+	return strings.EqualFold(pool.Spec.Organization, repo.GetOwner().GetLogin()) &&
+		(pool.Spec.Repository == "" || strings.EqualFold(pool.Spec.Repository, repo.GetName()))
+}
+
+// runURL derives the workflow run web URL from the job web URL
+func runURL(job *github.WorkflowJob) string {
+	if before, _, found := strings.Cut(job.GetHTMLURL(), "/job/"); found {
+		return before
+	}
+	return job.GetRunURL()
+}
+
+// WorkflowJobServer serves the WorkflowJobHandler. It runs on every replica, not only the leader.
+type WorkflowJobServer struct {
+	Addr    string
+	Path    string
+	Handler http.Handler
+}
+
+// Start listens for webhook deliveries until ctx is done
+func (s *WorkflowJobServer) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle(s.Path, s.Handler)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// NeedLeaderElection allows all replicas to receive deliveries
+func (s *WorkflowJobServer) NeedLeaderElection() bool {
+	return false
+}
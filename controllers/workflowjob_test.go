@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	"github.com/google/go-github/v59/github"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestWorkflowJobHandler(t *testing.T) {
+	const namespace = "someNamespace"
+	const pool = "somerunner"
+	const podName = "somerunner-pod-abcde"
+	const secret = "someSecret"
+	const unsigned = "unsigned"
+
+	runnerInstance := &v1alpha1.GithubActionRunnerInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: namespace},
+		Spec:       v1alpha1.GithubActionRunnerInstanceSpec{Pool: pool, PodName: podName},
+	}
+
+	runnerPool := &v1alpha1.GithubActionRunner{
+		ObjectMeta: metav1.ObjectMeta{Name: pool, Namespace: namespace},
+		Spec:       v1alpha1.GithubActionRunnerSpec{Organization: "someOrg", Repository: "someRepo"},
+	}
+	// an ordinal pod name repeats in another namespace, for a pool of another organization
+	otherInstance := runnerInstance.DeepCopy()
+	otherInstance.Namespace = "otherNamespace"
+	otherPool := runnerPool.DeepCopy()
+	otherPool.Namespace = "otherNamespace"
+	otherPool.Spec.Organization = "otherOrg"
+
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeBuilder.GroupVersion, &v1alpha1.GithubActionRunner{}, &v1alpha1.GithubActionRunnerList{}, &v1alpha1.GithubActionRunnerInstance{}, &v1alpha1.GithubActionRunnerInstanceList{})
+	// status writes of concurrent replicas must not conflict, and failing ones must not fail the delivery
+	failPatch := false
+	patchStatus := func(ctx context.Context, cl client.Client, subResource string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+		data, err := patch.Data(obj)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), "resourceVersion")
+		if failPatch {
+			return apierrors.NewConflict(schema.GroupResource{Resource: "githubactionrunnerinstances"}, obj.GetName(), errors.New("someConflict"))
+		}
+		return cl.SubResource(subResource).Patch(ctx, obj, patch, opts...)
+	}
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(runnerInstance, runnerPool, otherInstance, otherPool).WithStatusSubresource(runnerInstance).
+		WithIndex(&v1alpha1.GithubActionRunnerInstance{}, runnerInstancePodIndex, indexRunnerInstancePod).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: patchStatus}).Build()
+	handler := &WorkflowJobHandler{Client: cl, Log: zap.New(), Secret: []byte(secret)}
+
+	created := time.Now().Add(-time.Minute)
+	started := created.Add(30 * time.Second)
+	job := &github.WorkflowJob{
+		HTMLURL:    ptr.To("https://github.com/someOrg/someRepo/actions/runs/1/job/2"),
+		RunnerName: ptr.To(podName),
+		CreatedAt:  &github.Timestamp{Time: created},
+		StartedAt:  &github.Timestamp{Time: started},
+	}
+
+	repo := &github.Repository{Name: ptr.To("someRepo"), Owner: &github.User{Login: ptr.To("SomeOrg")}}
+	deliver := func(event *github.WorkflowJobEvent, signature string) int {
+		event.Repo = repo
+		body, err := json.Marshal(event)
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/github/webhook", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(github.EventTypeHeader, workflowJobEvent)
+		if signature == "" {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(body)
+			signature = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		}
+		if signature != unsigned {
+			req.Header.Set(github.SHA256SignatureHeader, signature)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, deliver(&github.WorkflowJobEvent{Action: ptr.To("in_progress"), WorkflowJob: job}, "sha256=00"))
+	assert.Equal(t, http.StatusBadRequest, deliver(&github.WorkflowJobEvent{Action: ptr.To("in_progress"), WorkflowJob: job}, unsigned))
+	// without a secret go-github would skip the signature check
+	handler.Secret = nil
+	assert.Equal(t, http.StatusForbidden, deliver(&github.WorkflowJobEvent{Action: ptr.To("in_progress"), WorkflowJob: job}, unsigned))
+	handler.Secret = []byte(secret)
+	assert.Equal(t, 0, testutil.CollectAndCount(jobQueueWait))
+
+	assert.Equal(t, http.StatusAccepted, deliver(&github.WorkflowJobEvent{Action: ptr.To("in_progress"), WorkflowJob: job}, ""))
+	assert.Equal(t, 1, testutil.CollectAndCount(jobQueueWait))
+
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: podName}, runnerInstance)
+	assert.NoError(t, err)
+	assert.Equal(t, job.GetHTMLURL(), runnerInstance.Status.JobURL)
+	assert.Equal(t, "https://github.com/someOrg/someRepo/actions/runs/1", runnerInstance.Status.RunURL)
+	failPatch = true
+	assert.Equal(t, http.StatusAccepted, deliver(&github.WorkflowJobEvent{Action: ptr.To("in_progress"), WorkflowJob: job}, ""))
+	failPatch = false
+
+	job.CompletedAt = &github.Timestamp{Time: started.Add(time.Minute)}
+	job.Conclusion = ptr.To("success")
+	assert.Equal(t, http.StatusAccepted, deliver(&github.WorkflowJobEvent{Action: ptr.To("completed"), WorkflowJob: job}, ""))
+	assert.Equal(t, 1, testutil.CollectAndCount(jobDuration))
+	assert.Equal(t, float64(1), testutil.ToFloat64(jobsServed.WithLabelValues(namespace, pool, "success")))
+	assert.NoError(t, cl.Get(context.TODO(), client.ObjectKeyFromObject(otherInstance), otherInstance))
+	assert.Empty(t, otherInstance.Status.JobURL)
+
+	// runners of two pools registering at the repository cannot be told apart
+	otherPool.Spec.Organization = "someOrg"
+	assert.NoError(t, cl.Update(context.TODO(), otherPool))
+	assert.Equal(t, http.StatusAccepted, deliver(&github.WorkflowJobEvent{Action: ptr.To("completed"), WorkflowJob: job}, ""))
+	assert.Equal(t, float64(1), testutil.ToFloat64(jobsServed.WithLabelValues(namespace, pool, "success")))
+}
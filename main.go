@@ -17,12 +17,17 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"go.uber.org/zap/zapcore"
 	"log"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/caitlinelfring/go-env-default"
+	"github.com/evryfs/github-actions-runner-operator/controllers/credentialprovider"
 	"github.com/evryfs/github-actions-runner-operator/controllers/githubapi"
 	"github.com/redhat-cop/operator-utils/pkg/util"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -34,6 +39,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	garov1alpha1 "github.com/evryfs/github-actions-runner-operator/api/v1alpha1"
+	garov1beta1 "github.com/evryfs/github-actions-runner-operator/api/v1beta1"
 	"github.com/evryfs/github-actions-runner-operator/controllers"
 	// +kubebuilder:scaffold:imports
 )
@@ -47,6 +53,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(garov1alpha1.AddToScheme(scheme))
+	utilruntime.Must(garov1beta1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -54,11 +61,13 @@ func main() {
 	var metricsAddr string
 	var healthProbeAddr string
 	var enableLeaderElection bool
+	var githubWebhookAddr string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&healthProbeAddr, "health-probe-addr", ":8081", "The address the health probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&githubWebhookAddr, "github-webhook-addr", "", "The address the GitHub workflow_job webhook endpoint binds to. Disabled if empty.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true), zap.JSONEncoder(func(encoderconfig *zapcore.EncoderConfig) {
@@ -97,16 +106,70 @@ func main() {
 		log.Panic(err)
 	}
 
+	// sources outside of the cluster are not watched, their secrets are cached for a while instead of read on every call
+	credentialsCacheTTL := env.GetDurationDefault("GARO_CREDENTIALS_CACHE_TTL", time.Minute)
+	credentialsProviders := map[garov1alpha1.CredentialsSource]credentialprovider.Provider{}
+	if vaultConfig := credentialprovider.VaultConfigFromEnv(); vaultConfig.Address != "" {
+		credentialsProviders[garov1alpha1.VaultSource] = credentialprovider.NewCachingProvider(credentialprovider.NewVaultProvider(vaultConfig), credentialsCacheTTL)
+	}
+	if credentialsDir := os.Getenv("GARO_CREDENTIALS_DIR"); credentialsDir != "" {
+		credentialsProviders[garov1alpha1.FileSource] = credentialprovider.NewCachingProvider(credentialprovider.FileProvider{Dir: credentialsDir}, credentialsCacheTTL)
+	}
+
 	if err = (&controllers.GithubActionRunnerReconciler{
-		ReconcilerBase: util.NewFromManager(mgr, mgr.GetEventRecorderFor("GithubActionRunner")),
-		Log:            ctrl.Log.WithName("controllers").WithName("GithubActionRunner"),
-		GithubAPI:      githubAPI,
+		ReconcilerBase:       util.NewFromManager(mgr, mgr.GetEventRecorderFor("GithubActionRunner")),
+		Log:                  ctrl.Log.WithName("controllers").WithName("GithubActionRunner"),
+		GithubAPI:            githubAPI,
+		CredentialsProviders: credentialsProviders,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "GithubActionRunner")
 		os.Exit(1)
 	}
+	if err = (&controllers.RunnerPodReconciler{
+		ReconcilerBase:       util.NewFromManager(mgr, mgr.GetEventRecorderFor("RunnerPod")),
+		Log:                  ctrl.Log.WithName("controllers").WithName("RunnerPod"),
+		GithubAPI:            githubAPI,
+		CredentialsProviders: credentialsProviders,
+		ForceReleaseAfter:    env.GetDurationDefault("GARO_FORCE_RELEASE_TIMEOUT", 0),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RunnerPod")
+		os.Exit(1)
+	}
+	// the webhook server needs certificates, see config/default for deploying it with cert-manager.
+	// Set ENABLE_WEBHOOKS=false to run without them, like when running locally.
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&garov1alpha1.GithubActionRunner{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GithubActionRunner")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
+	if githubWebhookAddr != "" {
+		// go-github skips the signature check without a secret, anyone reaching the endpoint could forge deliveries
+		webhookSecret := os.Getenv("GITHUB_WEBHOOK_SECRET")
+		if webhookSecret == "" {
+			setupLog.Error(errors.New("GITHUB_WEBHOOK_SECRET is not set"), "unable to add GitHub webhook server")
+			os.Exit(1)
+		}
+		if err = controllers.IndexRunnerInstancePods(context.Background(), mgr.GetFieldIndexer()); err != nil {
+			setupLog.Error(err, "unable to add GitHub webhook server")
+			os.Exit(1)
+		}
+		if err = mgr.Add(&controllers.WorkflowJobServer{
+			Addr: githubWebhookAddr,
+			Path: "/github/webhook",
+			Handler: &controllers.WorkflowJobHandler{
+				Client: mgr.GetClient(),
+				Log:    ctrl.Log.WithName("webhooks").WithName("WorkflowJob"),
+				Secret: []byte(webhookSecret),
+			},
+		}); err != nil {
+			setupLog.Error(err, "unable to add GitHub webhook server")
+			os.Exit(1)
+		}
+	}
+
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")